@@ -0,0 +1,114 @@
+package blockio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io"
+)
+
+// ErrChainBroken is returned when a block does not reference the hash of its predecessor.
+var ErrChainBroken = errors.New("hash chain broken")
+
+// A ChainWriter prepends to each block the SHA-256 of the previous block before writing it,
+// making a tamper-evident append-only chain of blocks.
+type ChainWriter struct {
+	dst  io.Writer
+	head [sha256.Size]byte
+	buf  []byte
+}
+
+// NewChainWriter returns a new ChainWriter writing blocks to w.
+// Provided w must be a block writer.
+// The first block references a zeroed hash.
+func NewChainWriter(w io.Writer) *ChainWriter {
+	return NewChainWriterFrom(w, [sha256.Size]byte{})
+}
+
+// NewChainWriterFrom is like NewChainWriter but continues the chain from head
+// (e.g. the head returned by VerifyChain when appending to an existing stream).
+func NewChainWriterFrom(w io.Writer, head [sha256.Size]byte) *ChainWriter {
+	return &ChainWriter{
+		dst:  w,
+		head: head,
+	}
+}
+
+// Write writes the block preceded by the current head and moves the head to the hash of the written block.
+func (w *ChainWriter) Write(block []byte) (n int, err error) {
+	w.buf = append(w.buf[:0], w.head[:]...)
+	w.buf = append(w.buf, block...)
+
+	if _, err = w.dst.Write(w.buf); err != nil {
+		return 0, err
+	}
+
+	w.head = sha256.Sum256(w.buf)
+	return len(block), nil
+}
+
+// Head returns the hash of the last written block.
+// Publishing it allows to detect any later rewrite of the chain.
+func (w *ChainWriter) Head() [sha256.Size]byte {
+	return w.head
+}
+
+// A ChainReader verifies and strips the hash chain of blocks written by a ChainWriter.
+type ChainReader struct {
+	src     io.Reader
+	buf     []byte
+	head    [sha256.Size]byte
+	ordinal uint64
+}
+
+// NewChainReader returns a new ChainReader reading blocks of size up to size from r.
+// Provided r must be a block reader.
+// A block not referencing its predecessor is reported by a *BlockError wrapping ErrChainBroken.
+func NewChainReader(r io.Reader, size int) *ChainReader {
+	return &ChainReader{
+		src: r,
+		buf: make([]byte, size),
+	}
+}
+
+func (r *ChainReader) Read(p []byte) (n int, err error) {
+	n, err = r.src.Read(r.buf[:cap(r.buf)])
+	if err != nil {
+		return 0, err
+	}
+	if n < sha256.Size || !bytes.Equal(r.buf[:sha256.Size], r.head[:]) {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrChainBroken}
+	}
+
+	data := r.buf[sha256.Size:n]
+	if len(data) > cap(p) {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	r.head = sha256.Sum256(r.buf[:n])
+	r.ordinal++
+	return copy(p[:cap(p)], data), nil
+}
+
+// Head returns the hash of the last read block.
+func (r *ChainReader) Head() [sha256.Size]byte {
+	return r.head
+}
+
+// VerifyChain walks all the blocks of size up to size written by a ChainWriter to r.
+// Provided r must be a block reader.
+// It returns the head of the chain and the number of blocks, or the first break as a *BlockError wrapping ErrChainBroken.
+func VerifyChain(r io.Reader, size int) (head [sha256.Size]byte, count uint64, err error) {
+	cr := NewChainReader(r, size)
+	buf := make([]byte, size)
+
+	for {
+		_, err = cr.Read(buf)
+		if errors.Is(err, io.EOF) {
+			return cr.head, cr.ordinal, nil
+		}
+		if err != nil {
+			return cr.head, cr.ordinal, err
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package blockio_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainWriter_Write(t *testing.T) {
+	var buf bytes.Buffer
+	w := blockio.NewChainWriter(blockio.NewWriter16(&buf))
+
+	var heads [][sha256.Size]byte
+	for _, block := range []string{"data", "datum", "last"} {
+		n, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+		assert.Equal(t, len(block), n)
+		heads = append(heads, w.Head())
+	}
+	assert.NotEqual(t, heads[0], heads[1])
+	data := buf.Bytes()
+
+	//
+	// Read
+
+	r := blockio.NewChainReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16)
+	blocks, err := readAll(r, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "datum", "last"}, blocks)
+	assert.Equal(t, w.Head(), r.Head())
+
+	//
+	// Verify
+
+	head, count, err := blockio.VerifyChain(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, w.Head(), head)
+
+	//
+	// Append to an existing chain
+
+	w = blockio.NewChainWriterFrom(blockio.NewWriter16(&buf), head)
+	_, err = w.Write([]byte("appended"))
+	assert.NoError(t, err)
+
+	head, count, err = blockio.VerifyChain(blockio.NewReader16(bytes.NewReader(buf.Bytes())), blockio.MaxBlock16)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(4), count)
+	assert.Equal(t, w.Head(), head)
+}
+
+func TestVerifyChain(t *testing.T) {
+	var buf bytes.Buffer
+	w := blockio.NewChainWriter(blockio.NewWriter16(&buf))
+	for _, block := range []string{"data", "datum", "last"} {
+		_, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+	}
+	blocks := rawBlocks(t, buf.Bytes())
+
+	//
+	// Rewritten block: its hash is valid but the next block breaks the chain
+
+	rewritten := append([]byte(nil), blocks[1]...)
+	copy(rewritten[sha256.Size:], "dotum")
+
+	_, count, err := blockio.VerifyChain(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[0], rewritten, blocks[2]))), blockio.MaxBlock16)
+	assert.ErrorIs(t, err, blockio.ErrChainBroken)
+	assert.Equal(t, uint64(2), count)
+
+	var berr *blockio.BlockError
+	if assert.ErrorAs(t, err, &berr) {
+		assert.Equal(t, uint64(2), berr.Index)
+	}
+
+	//
+	// Dropped block
+
+	_, _, err = blockio.VerifyChain(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[0], blocks[2]))), blockio.MaxBlock16)
+	assert.ErrorIs(t, err, blockio.ErrChainBroken)
+
+	//
+	// Rewritten tail is only detected with the published head
+
+	head, _, err := blockio.VerifyChain(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[0], blocks[1]))), blockio.MaxBlock16)
+	assert.NoError(t, err)
+	assert.NotEqual(t, w.Head(), head)
+}
+
+func TestChain_Encoder(t *testing.T) {
+	var buf bytes.Buffer
+	w := blockio.NewChainWriter(blockio.NewWriter16(&buf))
+
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write("audit"))
+	head := w.Head()
+
+	r := blockio.NewChainReader(blockio.NewReader16(&buf), blockio.MaxBlock16)
+	decoder := blockio.NewBlockDecoder(r, json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "audit", v)
+	assert.Equal(t, head, r.Head())
+}
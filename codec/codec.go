@@ -0,0 +1,139 @@
+// Package codec provides ready-made Encode/Decode pairs for blockio.
+package codec
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/mdouchement/blockio"
+)
+
+// ErrUnsupportedType is returned when a value cannot be handled by a codec.
+var ErrUnsupportedType = errors.New("unsupported type for the codec")
+
+// A Codec is a pair of Encode and Decode functions.
+type Codec struct {
+	Encode blockio.Encode
+	Decode blockio.Decode
+}
+
+// JSON returns a codec using encoding/json.
+func JSON() Codec {
+	return Codec{
+		Encode: json.Marshal,
+		Decode: json.Unmarshal,
+	}
+}
+
+// Gob returns a codec using encoding/gob.
+// Its Encode and Decode keep a gob stream each so type information is only sent in the first block of a given type.
+// A Gob codec must be used by only one Encoder and one Decoder, reading the blocks in the order they have been written.
+func Gob() Codec {
+	var ebuf bytes.Buffer
+	enc := gob.NewEncoder(&ebuf)
+
+	var dbuf bytes.Buffer
+	dec := gob.NewDecoder(&dbuf)
+
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			ebuf.Reset()
+			if err := enc.Encode(v); err != nil {
+				return nil, err
+			}
+			return append([]byte(nil), ebuf.Bytes()...), nil
+		},
+		Decode: func(data []byte, v any) error {
+			dbuf.Reset()
+			dbuf.Write(data)
+			return dec.Decode(v)
+		},
+	}
+}
+
+// Binary returns a codec for values implementing encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.
+func Binary() Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			m, ok := v.(encoding.BinaryMarshaler)
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return m.MarshalBinary()
+		},
+		Decode: func(data []byte, v any) error {
+			u, ok := v.(encoding.BinaryUnmarshaler)
+			if !ok {
+				return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return u.UnmarshalBinary(data)
+		},
+	}
+}
+
+// Text returns a codec for values implementing encoding.TextMarshaler and encoding.TextUnmarshaler.
+func Text() Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			m, ok := v.(encoding.TextMarshaler)
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return m.MarshalText()
+		},
+		Decode: func(data []byte, v any) error {
+			u, ok := v.(encoding.TextUnmarshaler)
+			if !ok {
+				return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return u.UnmarshalText(data)
+		},
+	}
+}
+
+// Bytes returns a codec writing []byte values as is and reading them in *[]byte values.
+// Decoded bytes are copied so they remain valid after the next read.
+func Bytes() Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			b, ok := v.([]byte)
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return b, nil
+		},
+		Decode: func(data []byte, v any) error {
+			b, ok := v.(*[]byte)
+			if !ok {
+				return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			*b = append((*b)[:0], data...)
+			return nil
+		},
+	}
+}
+
+// String returns a codec writing string values as is and reading them in *string values.
+func String() Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			s, ok := v.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			return []byte(s), nil
+		},
+		Decode: func(data []byte, v any) error {
+			s, ok := v.(*string)
+			if !ok {
+				return fmt.Errorf("%w: %T", ErrUnsupportedType, v)
+			}
+			*s = string(data)
+			return nil
+		},
+	}
+}
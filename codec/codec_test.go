@@ -0,0 +1,98 @@
+package codec_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/mdouchement/blockio/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+type data struct {
+	Field1 string
+	Field2 int
+}
+
+func roundtrip(t *testing.T, c codec.Codec, values []any, newValue func() any) []any {
+	t.Helper()
+
+	var buf bytes.Buffer
+	encoder := blockio.NewBlock16Encoder(&buf, c.Encode)
+	for _, v := range values {
+		assert.NoError(t, encoder.Write(v))
+	}
+
+	var decoded []any
+	decoder := blockio.NewBlock16Decoder(&buf, c.Decode)
+	for {
+		v := newValue()
+		err := decoder.Read(v)
+		if err == io.EOF {
+			return decoded
+		}
+		assert.NoError(t, err)
+		decoded = append(decoded, v)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	decoded := roundtrip(t, codec.JSON(), []any{data{"test", 42}}, func() any { return new(data) })
+	assert.Equal(t, []any{&data{"test", 42}}, decoded)
+}
+
+func TestGob(t *testing.T) {
+	c := codec.Gob()
+
+	b1, err := c.Encode(data{"test", 42})
+	assert.NoError(t, err)
+	b2, err := c.Encode(data{"tset", 24})
+	assert.NoError(t, err)
+	assert.Less(t, len(b2), len(b1)) // Type information only sent once.
+
+	var v data
+	assert.NoError(t, c.Decode(b1, &v))
+	assert.Equal(t, data{"test", 42}, v)
+	assert.NoError(t, c.Decode(b2, &v))
+	assert.Equal(t, data{"tset", 24}, v)
+
+	//
+
+	decoded := roundtrip(t, codec.Gob(), []any{data{"test", 42}, data{"tset", 24}}, func() any { return new(data) })
+	assert.Equal(t, []any{&data{"test", 42}, &data{"tset", 24}}, decoded)
+}
+
+func TestBinary(t *testing.T) {
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	decoded := roundtrip(t, codec.Binary(), []any{ts}, func() any { return new(time.Time) })
+	assert.Equal(t, []any{&ts}, decoded)
+
+	_, err := codec.Binary().Encode(42)
+	assert.ErrorIs(t, err, codec.ErrUnsupportedType)
+}
+
+func TestText(t *testing.T) {
+	ip := net.ParseIP("192.168.1.1")
+	decoded := roundtrip(t, codec.Text(), []any{ip}, func() any { return new(net.IP) })
+	assert.Equal(t, ip.String(), decoded[0].(*net.IP).String())
+
+	assert.ErrorIs(t, codec.Text().Decode([]byte("a"), new(int)), codec.ErrUnsupportedType)
+}
+
+func TestBytes(t *testing.T) {
+	decoded := roundtrip(t, codec.Bytes(), []any{[]byte("data"), []byte("datum")}, func() any { return new([]byte) })
+	assert.Equal(t, []byte("data"), *decoded[0].(*[]byte))
+	assert.Equal(t, []byte("datum"), *decoded[1].(*[]byte))
+}
+
+func TestString(t *testing.T) {
+	decoded := roundtrip(t, codec.String(), []any{"data", "datum"}, func() any { return new(string) })
+	assert.Equal(t, "data", *decoded[0].(*string))
+	assert.Equal(t, "datum", *decoded[1].(*string))
+
+	_, err := codec.String().Encode(42)
+	assert.ErrorIs(t, err, codec.ErrUnsupportedType)
+}
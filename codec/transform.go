@@ -0,0 +1,134 @@
+package codec
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"io"
+)
+
+// ErrCiphertextTooShort is returned when the data is too short to be opened by an AEAD transform.
+var ErrCiphertextTooShort = errors.New("ciphertext too short")
+
+// A Transform is a byte-to-byte stage with both of its directions declared together.
+type Transform struct {
+	// Forward is applied when encoding.
+	Forward func(data []byte) ([]byte, error)
+	// Reverse undoes Forward when decoding.
+	Reverse func(data []byte) ([]byte, error)
+}
+
+// Chain returns a codec applying c then all the stages in order when encoding,
+// and the stages in reverse order then c when decoding.
+//
+//	c := codec.Chain(codec.JSON(), codec.Gzip(gzip.BestSpeed), codec.AEAD(aead))
+func Chain(c Codec, stages ...Transform) Codec {
+	return Codec{
+		Encode: func(v any) ([]byte, error) {
+			data, err := c.Encode(v)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, stage := range stages {
+				data, err = stage.Forward(data)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			return data, nil
+		},
+		Decode: func(data []byte, v any) (err error) {
+			for i := len(stages) - 1; i >= 0; i-- {
+				data, err = stages[i].Reverse(data)
+				if err != nil {
+					return err
+				}
+			}
+
+			return c.Decode(data, v)
+		},
+	}
+}
+
+// Flate returns a transform compressing with compress/flate at the given level.
+func Flate(level int) Transform {
+	return compression(
+		func(w io.Writer) (io.WriteCloser, error) { return flate.NewWriter(w, level) },
+		func(r io.Reader) (io.ReadCloser, error) { return flate.NewReader(r), nil },
+	)
+}
+
+// Gzip returns a transform compressing with compress/gzip at the given level.
+func Gzip(level int) Transform {
+	return compression(
+		func(w io.Writer) (io.WriteCloser, error) { return gzip.NewWriterLevel(w, level) },
+		func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+	)
+}
+
+// Zlib returns a transform compressing with compress/zlib at the given level.
+func Zlib(level int) Transform {
+	return compression(
+		func(w io.Writer) (io.WriteCloser, error) { return zlib.NewWriterLevel(w, level) },
+		zlib.NewReader,
+	)
+}
+
+func compression(nw func(io.Writer) (io.WriteCloser, error), nr func(io.Reader) (io.ReadCloser, error)) Transform {
+	return Transform{
+		Forward: func(data []byte) ([]byte, error) {
+			var buf bytes.Buffer
+			w, err := nw(&buf)
+			if err != nil {
+				return nil, err
+			}
+
+			if _, err = w.Write(data); err != nil {
+				return nil, err
+			}
+			if err = w.Close(); err != nil {
+				return nil, err
+			}
+
+			return buf.Bytes(), nil
+		},
+		Reverse: func(data []byte) ([]byte, error) {
+			r, err := nr(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			defer r.Close()
+
+			return io.ReadAll(r)
+		},
+	}
+}
+
+// AEAD returns a transform sealing data with the given aead (e.g. AES-GCM).
+// A random nonce is generated for each block and prepended to the ciphertext.
+func AEAD(aead cipher.AEAD) Transform {
+	return Transform{
+		Forward: func(data []byte) ([]byte, error) {
+			nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(data)+aead.Overhead())
+			if _, err := rand.Read(nonce); err != nil {
+				return nil, err
+			}
+
+			return aead.Seal(nonce, nonce, data, nil), nil
+		},
+		Reverse: func(data []byte) ([]byte, error) {
+			if len(data) < aead.NonceSize() {
+				return nil, ErrCiphertextTooShort
+			}
+
+			nonce := data[:aead.NonceSize()]
+			return aead.Open(nil, nonce, data[aead.NonceSize():], nil)
+		},
+	}
+}
@@ -0,0 +1,69 @@
+package codec_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/aes"
+	"crypto/cipher"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/blockio/codec"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChain(t *testing.T) {
+	block, err := aes.NewCipher(bytes.Repeat([]byte{42}, 32))
+	assert.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+
+	for _, tt := range []struct {
+		name   string
+		stages []codec.Transform
+	}{
+		{name: "none"},
+		{name: "flate", stages: []codec.Transform{codec.Flate(flate.BestSpeed)}},
+		{name: "gzip", stages: []codec.Transform{codec.Gzip(gzip.DefaultCompression)}},
+		{name: "zlib+aead", stages: []codec.Transform{codec.Zlib(zlib.BestCompression), codec.AEAD(aead)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			v := data{Field1: strings.Repeat("test", 42), Field2: 42}
+
+			decoded := roundtrip(t, codec.Chain(codec.JSON(), tt.stages...), []any{v}, func() any { return new(data) })
+			assert.Equal(t, []any{&v}, decoded)
+		})
+	}
+
+	//
+	// Tampering
+
+	c := codec.Chain(codec.String(), codec.AEAD(aead))
+	payload, err := c.Encode("data")
+	assert.NoError(t, err)
+	payload[len(payload)-1] ^= 0xFF
+	assert.Error(t, c.Decode(payload, new(string)))
+	assert.ErrorIs(t, c.Decode(payload[:4], new(string)), codec.ErrCiphertextTooShort)
+
+	//
+	// Stages order
+
+	first := codec.Transform{
+		Forward: func(data []byte) ([]byte, error) { return append(data, '1'), nil },
+		Reverse: func(data []byte) ([]byte, error) { return bytes.TrimSuffix(data, []byte("1")), nil },
+	}
+	second := codec.Transform{
+		Forward: func(data []byte) ([]byte, error) { return append(data, '2'), nil },
+		Reverse: func(data []byte) ([]byte, error) { return bytes.TrimSuffix(data, []byte("2")), nil },
+	}
+	c = codec.Chain(codec.String(), first, second)
+	payload, err = c.Encode("data")
+	assert.NoError(t, err)
+	assert.Equal(t, "data12", string(payload))
+
+	var s string
+	assert.NoError(t, c.Decode(payload, &s))
+	assert.Equal(t, "data", s)
+}
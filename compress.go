@@ -0,0 +1,214 @@
+package blockio
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+)
+
+// Compression algorithms stored in the first byte of each compressed block.
+const (
+	CompressionNone Compression = iota
+	CompressionFlate
+	CompressionGzip
+	CompressionZlib
+)
+
+var (
+	// ErrUnknownCompression is returned when a block is compressed with an unknown algorithm.
+	ErrUnknownCompression = errors.New("unknown compression algorithm")
+	// ErrDecompressedTooLarge is returned when a block decompresses to more than the reader's maximum size.
+	ErrDecompressedTooLarge = errors.New("decompressed block too large")
+)
+
+// Compression is an algorithm used to compress blocks.
+type Compression byte
+
+const compressionMask = 0x0F // Lower bits are the algorithm, upper bits are reserved for flags.
+
+type compressWriter struct {
+	dst  io.Writer
+	algo Compression
+	buf  bytes.Buffer
+	cw   interface {
+		io.WriteCloser
+		Reset(io.Writer)
+	}
+}
+
+// NewCompressWriter returns a new writer that compresses each block with c at the given level before writing it to w.
+// Provided w must be a block writer.
+// A block is stored uncompressed when compression does not reduce its size.
+func NewCompressWriter(w io.Writer, c Compression, level int) (io.Writer, error) {
+	cw := &compressWriter{
+		dst:  w,
+		algo: c,
+	}
+
+	var err error
+	switch c {
+	case CompressionNone:
+	case CompressionFlate:
+		cw.cw, err = flate.NewWriter(&cw.buf, level)
+	case CompressionGzip:
+		cw.cw, err = gzip.NewWriterLevel(&cw.buf, level)
+	case CompressionZlib:
+		cw.cw, err = zlib.NewWriterLevel(&cw.buf, level)
+	default:
+		return nil, ErrUnknownCompression
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+func (w *compressWriter) Write(block []byte) (n int, err error) {
+	w.buf.Reset()
+	w.buf.WriteByte(byte(w.algo))
+
+	if w.cw != nil {
+		w.cw.Reset(&w.buf)
+		if _, err = w.cw.Write(block); err != nil {
+			return 0, err
+		}
+		if err = w.cw.Close(); err != nil {
+			return 0, err
+		}
+	}
+
+	if w.cw == nil || w.buf.Len() > len(block)+1 {
+		// Compression does not help, store as is.
+		w.buf.Reset()
+		w.buf.WriteByte(byte(CompressionNone))
+		w.buf.Write(block)
+	}
+
+	if _, err = w.dst.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(block), nil
+}
+
+type compressReader struct {
+	src io.Reader
+	buf []byte
+	max int
+	br  bytes.Reader
+	dec map[Compression]io.ReadCloser
+}
+
+// NewCompressReader returns a new reader that decompresses blocks of size up to size read from r.
+// Provided r must be a block reader.
+// Blocks decompressing to more than max bytes are rejected with ErrDecompressedTooLarge
+// and the buffer given to Read must be able to hold max bytes.
+func NewCompressReader(r io.Reader, size, max int) io.Reader {
+	return &compressReader{
+		src: r,
+		buf: make([]byte, size),
+		max: max,
+		dec: map[Compression]io.ReadCloser{},
+	}
+}
+
+func (r *compressReader) Read(p []byte) (n int, err error) {
+	if cap(p) < r.max {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	n, err = r.src.Read(r.buf[:cap(r.buf)])
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if r.buf[0]&^compressionMask != 0 {
+		return 0, ErrUnknownCompression // No flag is defined yet.
+	}
+	algo := Compression(r.buf[0])
+	data := r.buf[1:n]
+
+	if algo == CompressionNone {
+		if len(data) > r.max {
+			return 0, ErrDecompressedTooLarge
+		}
+		return copy(p[:r.max], data), nil
+	}
+
+	r.br.Reset(data)
+	dec, err := r.decompressor(algo)
+	if err != nil {
+		return 0, err
+	}
+
+	return readDecompressed(dec, p[:r.max])
+}
+
+// readDecompressed reads all the data of dec in p and fails if it does not fit.
+// Only io.EOF ends the data, a truncated or corrupted block fails with the error of dec (e.g. io.ErrUnexpectedEOF).
+func readDecompressed(dec io.Reader, p []byte) (n int, err error) {
+	lr := io.LimitReader(dec, int64(len(p))+1) // One more byte to detect the data that does not fit.
+
+	for n < len(p) {
+		m, err := lr.Read(p[n:])
+		n += m
+		switch {
+		case err == io.EOF:
+			return n, nil
+		case err != nil:
+			return 0, err
+		}
+	}
+
+	// p is full, ensure that there is nothing left.
+	var peek [1]byte
+	for {
+		m, err := lr.Read(peek[:])
+		switch {
+		case m > 0:
+			return 0, ErrDecompressedTooLarge
+		case err == io.EOF:
+			return n, nil
+		case err != nil:
+			return 0, err
+		}
+	}
+}
+
+// decompressor returns the decompressor of algo reset on the current block.
+func (r *compressReader) decompressor(algo Compression) (io.ReadCloser, error) {
+	dec, ok := r.dec[algo]
+	if !ok {
+		var err error
+		switch algo {
+		case CompressionFlate:
+			dec = flate.NewReader(&r.br)
+		case CompressionGzip:
+			dec, err = gzip.NewReader(&r.br)
+		case CompressionZlib:
+			dec, err = zlib.NewReader(&r.br)
+		default:
+			return nil, ErrUnknownCompression
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		r.dec[algo] = dec
+		return dec, nil
+	}
+
+	switch d := dec.(type) {
+	case *gzip.Reader:
+		return d, d.Reset(&r.br)
+	case flate.Resetter:
+		return dec, d.Reset(&r.br, nil)
+	}
+	return dec, nil
+}
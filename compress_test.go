@@ -0,0 +1,125 @@
+package blockio_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressWriter_Write(t *testing.T) {
+	for _, c := range []blockio.Compression{blockio.CompressionNone, blockio.CompressionFlate, blockio.CompressionGzip, blockio.CompressionZlib} {
+		var buf bytes.Buffer
+		w, err := blockio.NewCompressWriter(blockio.NewWriter16(&buf), c, flate.BestCompression)
+		assert.NoError(t, err)
+
+		//
+		// Compressed
+
+		data := []byte(strings.Repeat("data", 1000))
+		n, err := w.Write(data)
+		assert.NoError(t, err)
+		assert.Equal(t, len(data), n)
+
+		r := blockio.NewCompressReader(blockio.NewReader16(&buf), blockio.MaxBlock16, 4000)
+		block := make([]byte, 4000)
+		n, err = r.Read(block)
+		assert.NoError(t, err)
+		assert.Equal(t, data, block[:n])
+
+		//
+		// Tiny block stored as is
+
+		buf.Reset()
+		_, err = w.Write([]byte("a"))
+		assert.NoError(t, err)
+		assert.Equal(t, []byte{0, 2, byte(blockio.CompressionNone), 'a'}, buf.Bytes())
+
+		n, err = r.Read(block)
+		assert.NoError(t, err)
+		assert.Equal(t, "a", string(block[:n]))
+
+		_, err = r.Read(block)
+		assert.ErrorIs(t, err, io.EOF)
+	}
+
+	_, err := blockio.NewCompressWriter(io.Discard, 42, 0)
+	assert.ErrorIs(t, err, blockio.ErrUnknownCompression)
+}
+
+func TestCompressReader_Read(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := blockio.NewCompressWriter(blockio.NewWriter16(&buf), blockio.CompressionGzip, flate.DefaultCompression)
+	assert.NoError(t, err)
+	_, err = w.Write(make([]byte, 1<<20))
+	assert.NoError(t, err)
+
+	//
+	// Decompression bomb
+
+	r := blockio.NewCompressReader(blockio.NewReader16(&buf), blockio.MaxBlock16, blockio.MaxBlock16)
+	_, err = r.Read(make([]byte, blockio.MaxBlock16-1))
+	assert.ErrorIs(t, err, blockio.ErrBlockSizeTooSmall)
+	_, err = r.Read(make([]byte, blockio.MaxBlock16))
+	assert.ErrorIs(t, err, blockio.ErrDecompressedTooLarge)
+
+	//
+	// Truncated block
+
+	for _, c := range []blockio.Compression{blockio.CompressionFlate, blockio.CompressionGzip, blockio.CompressionZlib} {
+		var buf bytes.Buffer
+		w, err := blockio.NewCompressWriter(blockio.NewWriter16(&buf), c, flate.BestCompression)
+		assert.NoError(t, err)
+		_, err = w.Write([]byte(strings.Repeat("data", 1000)))
+		assert.NoError(t, err)
+
+		r := blockio.NewCompressReader(blockio.NewReader16(bytes.NewReader(truncateBlock16(t, buf.Bytes(), 3))), blockio.MaxBlock16, 4000)
+		_, err = r.Read(make([]byte, 4000))
+		assert.ErrorIs(t, err, io.ErrUnexpectedEOF, c)
+	}
+
+	//
+	// Unknown algorithm
+
+	r = blockio.NewCompressReader(blockio.NewReader16(bytes.NewReader([]byte{0, 2, 0x0F, 'a'})), blockio.MaxBlock16, 8)
+	_, err = r.Read(make([]byte, 8))
+	assert.ErrorIs(t, err, blockio.ErrUnknownCompression)
+
+	//
+	// Reserved flags
+
+	r = blockio.NewCompressReader(blockio.NewReader16(bytes.NewReader([]byte{0, 2, 0x10, 'a'})), blockio.MaxBlock16, 8)
+	_, err = r.Read(make([]byte, 8))
+	assert.ErrorIs(t, err, blockio.ErrUnknownCompression)
+}
+
+func TestCompress_Encoder(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := blockio.NewCompressWriter(blockio.NewWriter16(&buf), blockio.CompressionFlate, flate.BestSpeed)
+	assert.NoError(t, err)
+
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write(strings.Repeat("test", 42)))
+
+	decoder := blockio.NewBlockDecoder(blockio.NewCompressReader(blockio.NewReader16(&buf), blockio.MaxBlock16, blockio.MaxBlock16), json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, strings.Repeat("test", 42), v)
+}
+
+// truncateBlock16 removes the last n bytes of the data of the Block16 framed in stream.
+func truncateBlock16(t *testing.T, stream []byte, n int) []byte {
+	block := make([]byte, blockio.MaxBlock16)
+	size, err := blockio.NewReader16(bytes.NewReader(stream)).Read(block)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	_, err = blockio.NewWriter16(&buf).Write(block[:size-n])
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
@@ -0,0 +1,449 @@
+package blockio
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxMessageSize is the maximum size of a message used when ConnConfig.MaxMessageSize is not set.
+const DefaultMaxMessageSize = 1 << 20
+
+// Kinds of the frames of a Conn: [size][kind][payload].
+const (
+	connData byte = iota
+	connPing
+	connPong
+)
+
+var (
+	// ErrMessageTooLarge is returned when a message exceeds the maximum message size of a Conn.
+	ErrMessageTooLarge = errors.New("message too large")
+	// ErrHeartbeatTimeout is returned once a Conn has been closed because the peer did not answer a ping in time.
+	ErrHeartbeatTimeout = errors.New("heartbeat timeout")
+)
+
+// A ConnConfig configures a Conn.
+type ConnConfig struct {
+	// MaxMessageSize is the maximum size in bytes of the messages sent and received (default DefaultMaxMessageSize).
+	// It can not exceed MaxBlock32-1.
+	MaxMessageSize int
+	// Encode is used by Conn.Encode (default json.Marshal).
+	Encode Encode
+	// Decode is used by Conn.Decode (default json.Unmarshal).
+	Decode Decode
+	// PingInterval is the interval between the pings sent to the peer, zero disables the heartbeat.
+	PingInterval time.Duration
+	// PongTimeout is the time allowed to the peer to answer a ping (default PingInterval).
+	// Once exceeded, the connection is closed and all the calls fail with ErrHeartbeatTimeout.
+	PongTimeout time.Duration
+}
+
+func (cfg *ConnConfig) normalize() error {
+	if cfg.MaxMessageSize <= 0 {
+		cfg.MaxMessageSize = DefaultMaxMessageSize
+	}
+	if cfg.MaxMessageSize > MaxBlock32-1 { // One byte is used by the frame kind.
+		return ErrSizeTooLarge
+	}
+	if cfg.Encode == nil {
+		cfg.Encode = json.Marshal
+	}
+	if cfg.Decode == nil {
+		cfg.Decode = json.Unmarshal
+	}
+	if cfg.PongTimeout <= 0 {
+		cfg.PongTimeout = cfg.PingInterval
+	}
+	return nil
+}
+
+////////////////////////////
+//                        //
+// Conn                   //
+//                        //
+////////////////////////////
+
+// A Conn exchanges messages over a net.Conn, each message being sent in a Block32 frame.
+// Reads and writes can be performed concurrently.
+// A Conn is also a block reader and writer of messages, e.g. for NewBlockDecoder and NewBlockEncoder.
+//
+// A failure occurring in the middle of a message (e.g. a deadline exceeded after a partial read) is sticky
+// because the stream is no longer synchronized. A deadline exceeded before any byte is read or written is not.
+//
+// When the heartbeat is enabled, pings are sent at ConnConfig.PingInterval. The pings of the peer are answered
+// and the control frames are filtered out while reading messages, so the connection must be read continuously.
+type Conn struct {
+	// Accessed atomically, first in the struct for the 64-bit alignment.
+	rn int64 // Bytes consumed by the last frame read.
+	wn int64 // Bytes written by the last message write.
+
+	conn net.Conn
+	cfg  ConnConfig
+
+	rmu  sync.Mutex
+	r    *reader
+	rbuf []byte
+	rerr error // Sticky read error.
+
+	wmu  sync.Mutex
+	w    io.Writer
+	wbuf []byte
+	werr error // Sticky write error.
+
+	mu     sync.Mutex
+	err    error // Sticky error of a closed connection.
+	pong   chan struct{}
+	closed chan struct{}
+}
+
+// NewConn returns a new Conn over c.
+func NewConn(c net.Conn, cfg ConnConfig) (*Conn, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+
+	r, err := NewReader32Custom(c, cfg.MaxMessageSize+1)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter32Custom(c, cfg.MaxMessageSize+1)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &Conn{
+		conn:   c,
+		cfg:    cfg,
+		r:      r.(*reader),
+		w:      w,
+		pong:   make(chan struct{}, 1),
+		closed: make(chan struct{}),
+	}
+	if cfg.PingInterval > 0 {
+		go conn.heartbeat()
+	}
+	return conn, nil
+}
+
+// Dial connects to the address on the named network (see net.Dial) and returns a new Conn.
+func Dial(network, address string, cfg ConnConfig) (*Conn, error) {
+	c, err := net.Dial(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := NewConn(c, cfg)
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// ReadMessage reads the next message.
+// The returned slice is only valid until the next call to ReadMessage or Decode.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	return c.readMessage()
+}
+
+func (c *Conn) readMessage() ([]byte, error) {
+	if c.rerr != nil {
+		return nil, c.rerr
+	}
+	if c.rbuf == nil {
+		c.rbuf = make([]byte, c.cfg.MaxMessageSize+1)
+	}
+
+	for {
+		n, err := c.r.Read(c.rbuf)
+		atomic.StoreInt64(&c.rn, int64(c.r.consumed()))
+		if err == nil && n == 0 {
+			err = ErrInvalidFrame
+		}
+		if err != nil {
+			if cerr := c.Err(); cerr != nil {
+				err = cerr
+			}
+			if errors.Is(err, ErrSizeTooLarge) {
+				err = ErrMessageTooLarge
+			}
+			if c.r.consumed() > 0 || !isTimeout(err) {
+				c.rerr = err
+			}
+			return nil, err
+		}
+
+		switch c.rbuf[0] {
+		case connData:
+			return c.rbuf[1:n], nil
+		case connPing:
+			if err = c.writeFrame(connPong, nil); err != nil {
+				return nil, err
+			}
+		case connPong:
+			select {
+			case c.pong <- struct{}{}:
+			default:
+			}
+		default:
+			c.rerr = ErrInvalidFrame
+			return nil, c.rerr
+		}
+	}
+}
+
+// WriteMessage writes p as a single message.
+func (c *Conn) WriteMessage(p []byte) error {
+	atomic.StoreInt64(&c.wn, 0)
+	if len(p) > c.cfg.MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+
+	return c.writeFrame(connData, p)
+}
+
+func (c *Conn) writeFrame(kind byte, p []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	if c.werr != nil {
+		return c.werr
+	}
+
+	c.wbuf = append(c.wbuf[:0], kind)
+	c.wbuf = append(c.wbuf, p...)
+
+	n, err := c.w.Write(c.wbuf)
+	if kind == connData {
+		atomic.StoreInt64(&c.wn, int64(n))
+	}
+	if err != nil {
+		if cerr := c.Err(); cerr != nil {
+			err = cerr
+		}
+		if n > 0 || !isTimeout(err) {
+			c.werr = err
+		}
+		return err
+	}
+	return nil
+}
+
+// Read reads the next message in p, it implements a block reader of messages.
+// Provided p must be able to handle MaxMessageSize bytes.
+func (c *Conn) Read(p []byte) (int, error) {
+	if cap(p) < c.cfg.MaxMessageSize {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	data, err := c.readMessage()
+	if err != nil {
+		return 0, err
+	}
+	return copy(p[:cap(p)], data), nil
+}
+
+// Write writes p as a single message, it implements a block writer of messages.
+func (c *Conn) Write(p []byte) (int, error) {
+	if err := c.WriteMessage(p); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// consumed returns the number of bytes consumed from the connection by the last frame read.
+func (c *Conn) consumed() int {
+	return int(atomic.LoadInt64(&c.rn))
+}
+
+// written returns the number of bytes written to the connection by the last message write.
+func (c *Conn) written() int {
+	return int(atomic.LoadInt64(&c.wn))
+}
+
+// Encode encodes v with the configured Encode and writes it as a single message.
+func (c *Conn) Encode(v any) error {
+	payload, err := c.cfg.Encode(v)
+	if err != nil {
+		return err
+	}
+
+	return c.WriteMessage(payload)
+}
+
+// Decode reads the next message and decodes it in v with the configured Decode.
+func (c *Conn) Decode(v any) error {
+	c.rmu.Lock()
+	defer c.rmu.Unlock()
+
+	data, err := c.readMessage()
+	if err != nil {
+		return err
+	}
+	return c.cfg.Decode(data, v)
+}
+
+// SetDeadline sets both the read and write deadlines of the connection.
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// SetReadDeadline sets the deadline for ReadMessage and Decode.
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for WriteMessage and Encode.
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return c.conn.SetWriteDeadline(t)
+}
+
+// LocalAddr returns the local network address.
+func (c *Conn) LocalAddr() net.Addr {
+	return c.conn.LocalAddr()
+}
+
+// RemoteAddr returns the remote network address.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.conn.RemoteAddr()
+}
+
+// NetConn returns the underlying net.Conn.
+func (c *Conn) NetConn() net.Conn {
+	return c.conn
+}
+
+// Close closes the underlying connection and stops the heartbeat.
+func (c *Conn) Close() error {
+	c.shutdown(net.ErrClosed)
+	return c.conn.Close()
+}
+
+// Err returns ErrHeartbeatTimeout once the connection has been closed by the heartbeat, net.ErrClosed
+// once closed by Close, nil otherwise.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// heartbeat pings the peer at interval and closes the connection when a pong is not received in time.
+func (c *Conn) heartbeat() {
+	ticker := time.NewTicker(c.cfg.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-c.closed:
+			return
+		}
+
+		select {
+		case <-c.pong: // Drop a pong received late.
+		default:
+		}
+
+		// The ping is written asynchronously so a write blocked by the peer is detected too.
+		go c.writeFrame(connPing, nil) // A failure is reported by the next calls.
+
+		timer := time.NewTimer(c.cfg.PongTimeout)
+		select {
+		case <-c.pong:
+			timer.Stop()
+		case <-timer.C:
+			c.shutdown(ErrHeartbeatTimeout)
+			c.conn.Close()
+			return
+		case <-c.closed:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+func (c *Conn) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	c.err = err
+	close(c.closed)
+}
+
+func isTimeout(err error) bool {
+	var nerr net.Error
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
+
+////////////////////////////
+//                        //
+// Listener               //
+//                        //
+////////////////////////////
+
+// A Listener accepts connections as Conn.
+type Listener struct {
+	l   net.Listener
+	cfg ConnConfig
+}
+
+// NewListener returns a new Listener over l, the accepted connections use cfg.
+func NewListener(l net.Listener, cfg ConnConfig) (*Listener, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err
+	}
+
+	return &Listener{
+		l:   l,
+		cfg: cfg,
+	}, nil
+}
+
+// Listen announces on the local network address (see net.Listen) and returns a new Listener.
+func Listen(network, address string, cfg ConnConfig) (*Listener, error) {
+	if err := cfg.normalize(); err != nil {
+		return nil, err // Validated before binding the address.
+	}
+
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewListener(l, cfg) // Never fails because the config has already been validated.
+}
+
+// Accept waits for and returns the next connection.
+func (l *Listener) Accept() (*Conn, error) {
+	c, err := l.l.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewConn(c, l.cfg) // Never fails because the config has already been validated.
+}
+
+// Close closes the listener. Already accepted connections are not closed.
+func (l *Listener) Close() error {
+	return l.l.Close()
+}
+
+// Addr returns the listener's network address.
+func (l *Listener) Addr() net.Addr {
+	return l.l.Addr()
+}
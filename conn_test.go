@@ -0,0 +1,285 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func newConnPair(t *testing.T, client, server blockio.ConnConfig) (*blockio.Conn, *blockio.Conn) {
+	l, err := blockio.Listen("tcp", "127.0.0.1:0", server)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	accepted := make(chan *blockio.Conn, 1)
+	go func() {
+		c, err := l.Accept()
+		assert.NoError(t, err)
+		accepted <- c
+	}()
+
+	c, err := blockio.Dial("tcp", l.Addr().String(), client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, <-accepted
+}
+
+func TestConn_Message(t *testing.T) {
+	client, server := newConnPair(t, blockio.ConnConfig{}, blockio.ConnConfig{MaxMessageSize: 16})
+	defer client.Close()
+	defer server.Close()
+
+	//
+	// Both directions
+
+	assert.NoError(t, client.WriteMessage([]byte("ping")))
+	assert.NoError(t, client.WriteMessage(nil))
+	assert.NoError(t, client.WriteMessage([]byte("data")))
+
+	msg, err := server.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "ping", string(msg))
+	msg, err = server.ReadMessage()
+	assert.NoError(t, err)
+	assert.Empty(t, msg)
+	msg, err = server.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(msg))
+
+	assert.NoError(t, server.WriteMessage([]byte("pong")))
+	msg, err = client.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "pong", string(msg))
+
+	//
+	// Message too large for the writer
+
+	assert.ErrorIs(t, server.WriteMessage(bytes.Repeat([]byte{'a'}, 17)), blockio.ErrMessageTooLarge)
+	assert.NoError(t, server.WriteMessage([]byte("still usable")))
+	msg, err = client.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "still usable", string(msg))
+
+	//
+	// Message too large for the reader
+
+	assert.NoError(t, client.WriteMessage(bytes.Repeat([]byte{'a'}, 17)))
+	_, err = server.ReadMessage()
+	assert.ErrorIs(t, err, blockio.ErrMessageTooLarge)
+	_, err = server.ReadMessage()
+	assert.ErrorIs(t, err, blockio.ErrMessageTooLarge) // Sticky.
+}
+
+func TestConn_Encode(t *testing.T) {
+	client, server := newConnPair(t, blockio.ConnConfig{}, blockio.ConnConfig{})
+	defer client.Close()
+	defer server.Close()
+
+	type payload struct {
+		Name  string
+		Value int
+	}
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, client.Encode(payload{Name: "value", Value: i}))
+		}
+	}()
+
+	for i := 0; i < 10; i++ {
+		var v payload
+		assert.NoError(t, server.Decode(&v))
+		assert.Equal(t, payload{Name: "value", Value: i}, v)
+	}
+}
+
+func TestConn_Deadline(t *testing.T) {
+	client, server := newConnPair(t, blockio.ConnConfig{}, blockio.ConnConfig{})
+	defer client.Close()
+	defer server.Close()
+
+	//
+	// Read deadline exceeded before any message
+
+	assert.NoError(t, server.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	_, err := server.ReadMessage()
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	// Writes are not affected by the read deadline.
+	assert.NoError(t, server.WriteMessage([]byte("data")))
+	msg, err := client.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(msg))
+
+	// The connection is still usable once the deadline is cleared.
+	assert.NoError(t, server.SetReadDeadline(time.Time{}))
+	assert.NoError(t, client.WriteMessage([]byte("datum")))
+	msg, err = server.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "datum", string(msg))
+
+	//
+	// Read deadline exceeded in the middle of a message
+
+	_, err = client.NetConn().Write([]byte{0, 0, 0, 4, 'd'})
+	assert.NoError(t, err)
+	assert.NoError(t, server.SetReadDeadline(time.Now().Add(50*time.Millisecond)))
+	_, err = server.ReadMessage()
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+
+	assert.NoError(t, server.SetReadDeadline(time.Time{}))
+	_, err = server.ReadMessage()
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded) // Sticky.
+}
+
+func TestConn_Heartbeat(t *testing.T) {
+	client, server := newConnPair(t, blockio.ConnConfig{PingInterval: 5 * time.Millisecond}, blockio.ConnConfig{})
+	defer client.Close()
+	defer server.Close()
+
+	// The client reads continuously to receive the pongs.
+	replies := make(chan string)
+	go func() {
+		defer close(replies)
+		for {
+			msg, err := client.ReadMessage()
+			if err != nil {
+				return
+			}
+			replies <- string(msg)
+		}
+	}()
+
+	//
+	// Pings are filtered out before delivering the blocks to the decoder
+
+	go func() {
+		encoder := blockio.NewBlockEncoder(client, json.Marshal)
+		for i := 0; i < 5; i++ {
+			time.Sleep(10 * time.Millisecond)
+			assert.NoError(t, encoder.Write(i))
+		}
+	}()
+
+	decoder := blockio.NewBlockDecoder(server, json.Unmarshal, make([]byte, blockio.DefaultMaxMessageSize))
+	for i := 0; i < 5; i++ {
+		var v int
+		assert.NoError(t, decoder.Read(&v))
+		assert.Equal(t, i, v)
+	}
+
+	assert.NoError(t, server.WriteMessage([]byte("done")))
+	assert.Equal(t, "done", <-replies)
+	assert.NoError(t, client.Err())
+
+	//
+	// The peer stops answering
+
+	_, ok := <-replies
+	assert.False(t, ok)
+	assert.ErrorIs(t, client.Err(), blockio.ErrHeartbeatTimeout)
+	assert.ErrorIs(t, client.WriteMessage([]byte("data")), blockio.ErrHeartbeatTimeout)
+	_, err := client.ReadMessage()
+	assert.ErrorIs(t, err, blockio.ErrHeartbeatTimeout)
+}
+
+func TestConn_Read(t *testing.T) {
+	client, server := newConnPair(t, blockio.ConnConfig{MaxMessageSize: 16}, blockio.ConnConfig{MaxMessageSize: 16})
+	defer client.Close()
+	defer server.Close()
+
+	n, err := client.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+
+	_, err = server.Read(make([]byte, 15))
+	assert.ErrorIs(t, err, blockio.ErrBlockSizeTooSmall)
+
+	block := make([]byte, 16)
+	n, err = server.Read(block)
+	assert.NoError(t, err)
+	assert.Equal(t, "data", string(block[:n]))
+
+	assert.NoError(t, client.Close())
+	assert.ErrorIs(t, client.Err(), net.ErrClosed)
+	_, err = server.Read(block)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestNewConn(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	_, err := blockio.NewConn(c1, blockio.ConnConfig{MaxMessageSize: blockio.MaxBlock32})
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	_, err = blockio.NewListener(nil, blockio.ConnConfig{MaxMessageSize: blockio.MaxBlock32})
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	_, err = blockio.Listen("tcp", "127.0.0.1:0", blockio.ConnConfig{MaxMessageSize: blockio.MaxBlock32})
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	// The config is validated before binding the address.
+	_, err = blockio.Listen("tcp", "invalid address", blockio.ConnConfig{MaxMessageSize: blockio.MaxBlock32})
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+}
+
+func TestListener_Accept(t *testing.T) {
+	nl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := blockio.NewListener(nl, blockio.ConnConfig{})
+	assert.NoError(t, err)
+	defer l.Close()
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			c, err := l.Accept()
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer c.Close()
+				for {
+					msg, err := c.ReadMessage()
+					if err != nil {
+						return
+					}
+					c.WriteMessage(msg) // Echo.
+				}
+			}()
+		}
+	}()
+
+	for i := 0; i < 3; i++ {
+		c, err := blockio.Dial("tcp", l.Addr().String(), blockio.ConnConfig{})
+		assert.NoError(t, err)
+
+		assert.NoError(t, c.WriteMessage([]byte("echo")))
+		msg, err := c.ReadMessage()
+		assert.NoError(t, err)
+		assert.Equal(t, "echo", string(msg))
+		assert.NoError(t, c.Close())
+	}
+}
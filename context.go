@@ -0,0 +1,253 @@
+package blockio
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"time"
+)
+
+// ErrInterrupted is returned once a read or write has been interrupted in the middle of a block.
+var ErrInterrupted = errors.New("stream interrupted")
+
+// interruptedError is the sticky error of an interrupted stream.
+// It matches ErrInterrupted and wraps the error of the context.
+type interruptedError struct {
+	err error
+}
+
+func (e *interruptedError) Error() string {
+	return ErrInterrupted.Error() + ": " + e.err.Error()
+}
+
+func (e *interruptedError) Unwrap() error {
+	return e.err
+}
+
+func (e *interruptedError) Is(target error) bool {
+	return target == ErrInterrupted
+}
+
+type (
+	readDeadliner interface {
+		SetReadDeadline(t time.Time) error
+	}
+
+	writeDeadliner interface {
+		SetWriteDeadline(t time.Time) error
+	}
+
+	// readProgress is implemented by the block readers reporting the bytes consumed by their last Read.
+	readProgress interface {
+		consumed() int
+	}
+
+	// writeProgress is implemented by the block writers reporting the bytes written by their last Write.
+	writeProgress interface {
+		written() int
+	}
+)
+
+// ReadContext is like Read but aborts when ctx is done.
+//
+// When the block reader's source supports SetReadDeadline (e.g. net.Conn, Conn), the pending read is interrupted
+// through its deadline. If the block reader reports that no byte of the block has been consumed,
+// ctx.Err() is returned and the decoder remains usable.
+// Otherwise, or when deadlines are not supported, the stream is left in an undefined state
+// and this read and all the following ones return a sticky error matching both ErrInterrupted and ctx.Err().
+//
+// A read deadline set on the source is left untouched unless ctx has a deadline or is canceled,
+// in which case it is replaced for this read and cleared afterwards. The two deadlines are not combined.
+func (d *Decoder) ReadContext(ctx context.Context, v any) error {
+	if d.err != nil {
+		return d.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := d.readBlockContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return d.decode(data, v)
+}
+
+func (d *Decoder) readBlockContext(ctx context.Context) ([]byte, error) {
+	if ctx.Done() == nil {
+		return d.ReadBlock()
+	}
+
+	rd, ok := readDeadlinerOf(d.r)
+	if !ok {
+		// The read keeps running in background, the decoder must not be used anymore once interrupted.
+		type result struct {
+			data []byte
+			err  error
+		}
+		done := make(chan result, 1)
+		go func() {
+			n, err := d.r.Read(d.buf[:cap(d.buf)])
+			done <- result{data: d.buf[:n], err: err}
+		}()
+
+		select {
+		case r := <-done:
+			if r.err != nil {
+				return nil, r.err
+			}
+			return r.data, nil
+		case <-ctx.Done():
+			d.err = &interruptedError{err: ctx.Err()}
+			return nil, d.err
+		}
+	}
+
+	var data []byte
+	err := interruptible(ctx, rd.SetReadDeadline, func() (err error) {
+		data, err = d.ReadBlock()
+		return err
+	})
+
+	cerr := contextErr(ctx, err)
+	if err == nil || cerr == nil {
+		return data, err
+	}
+
+	if p, ok := d.r.(readProgress); ok && p.consumed() == 0 {
+		return nil, cerr // Nothing consumed, the stream is still on a block boundary.
+	}
+	d.err = &interruptedError{err: cerr}
+	return nil, d.err
+}
+
+// WriteContext is like Write but aborts when ctx is done.
+//
+// When the block writer's destination supports SetWriteDeadline (e.g. net.Conn, Conn), the pending write is interrupted
+// through its deadline. If the block writer reports that no byte of the block has been written,
+// ctx.Err() is returned and the encoder remains usable.
+// Otherwise, or when deadlines are not supported, the stream is left in an undefined state
+// and this write and all the following ones return a sticky error matching both ErrInterrupted and ctx.Err().
+//
+// A write deadline set on the destination is left untouched unless ctx has a deadline or is canceled,
+// in which case it is replaced for this write and cleared afterwards. The two deadlines are not combined.
+func (e *Encoder) WriteContext(ctx context.Context, v any) error {
+	if e.err != nil {
+		return e.err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	payload, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+
+	if ctx.Done() == nil {
+		_, err = e.w.Write(payload)
+		return err
+	}
+
+	wd, ok := writeDeadlinerOf(e.w)
+	if !ok {
+		// The write keeps running in background, the encoder must not be used anymore once interrupted.
+		done := make(chan error, 1)
+		go func() {
+			_, err := e.w.Write(payload)
+			done <- err
+		}()
+
+		select {
+		case err = <-done:
+			return err
+		case <-ctx.Done():
+			e.err = &interruptedError{err: ctx.Err()}
+			return e.err
+		}
+	}
+
+	err = interruptible(ctx, wd.SetWriteDeadline, func() error {
+		_, err := e.w.Write(payload)
+		return err
+	})
+
+	cerr := contextErr(ctx, err)
+	if err == nil || cerr == nil {
+		return err
+	}
+
+	if p, ok := e.w.(writeProgress); ok && p.written() == 0 {
+		return cerr // Nothing written, the stream is still on a block boundary.
+	}
+	e.err = &interruptedError{err: cerr}
+	return e.err
+}
+
+// readDeadlinerOf returns the deadline support of r, looking through the block readers of the package.
+func readDeadlinerOf(r io.Reader) (readDeadliner, bool) {
+	if br, ok := r.(*reader); ok {
+		r = br.src
+	}
+
+	rd, ok := r.(readDeadliner)
+	return rd, ok
+}
+
+// writeDeadlinerOf returns the deadline support of w, looking through the block writers of the package.
+func writeDeadlinerOf(w io.Writer) (writeDeadliner, bool) {
+	if bw, ok := w.(*writer); ok {
+		w = bw.dst
+	}
+
+	wd, ok := w.(writeDeadliner)
+	return wd, ok
+}
+
+// interruptible runs fn, interrupting it through setDeadline once ctx is done.
+// The deadline is only set when ctx has a deadline or is canceled, and cleared afterwards in this case.
+func interruptible(ctx context.Context, setDeadline func(t time.Time) error, fn func() error) error {
+	deadline, hasDeadline := ctx.Deadline()
+	if hasDeadline {
+		if err := setDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	var interrupted bool
+	stop := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			interrupted = true
+			setDeadline(time.Unix(1, 0)) // Unblock the pending call.
+		case <-stop:
+		}
+	}()
+
+	err := fn()
+	close(stop)
+	<-stopped
+
+	if hasDeadline || interrupted {
+		setDeadline(time.Time{})
+	}
+	return err
+}
+
+// contextErr returns the error of ctx when err has been caused by its cancellation or its deadline.
+func contextErr(ctx context.Context, err error) error {
+	if cerr := ctx.Err(); cerr != nil {
+		return cerr
+	}
+
+	// The deadline of the source may expire slightly before the one of the context.
+	if _, ok := ctx.Deadline(); ok && errors.Is(err, os.ErrDeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
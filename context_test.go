@@ -0,0 +1,176 @@
+package blockio_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_ReadContext(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	decoder := blockio.NewBlock16Decoder(c2, json.Unmarshal)
+	var v string
+
+	//
+	// Already canceled
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, decoder.ReadContext(ctx, &v), context.Canceled)
+
+	//
+	// Nothing received: the decoder remains usable
+
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := decoder.ReadContext(ctx, &v)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, blockio.ErrInterrupted)
+
+	go func() {
+		c1.Write([]byte("\x00\x06\"data\""))
+	}()
+	assert.NoError(t, decoder.ReadContext(context.Background(), &v))
+	assert.Equal(t, "data", v)
+
+	//
+	// Interrupted in the middle of a block: sticky error
+
+	go func() {
+		c1.Write([]byte("\x00\x06\"da"))
+	}()
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = decoder.ReadContext(ctx, &v)
+	assert.ErrorIs(t, err, blockio.ErrInterrupted)
+	assert.ErrorIs(t, err, context.Canceled)
+
+	assert.ErrorIs(t, decoder.Read(&v), blockio.ErrInterrupted)
+}
+
+func TestDecoder_ReadContextWithoutDeadline(t *testing.T) {
+	r, w := io.Pipe()
+	defer w.Close()
+
+	decoder := blockio.NewBlock16Decoder(r, json.Unmarshal)
+	var v string
+
+	go func() {
+		w.Write([]byte("\x00\x06\"data\""))
+	}()
+	assert.NoError(t, decoder.ReadContext(context.Background(), &v))
+	assert.Equal(t, "data", v)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := decoder.ReadContext(ctx, &v)
+	assert.ErrorIs(t, err, blockio.ErrInterrupted)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, decoder.Read(&v), blockio.ErrInterrupted)
+}
+
+func TestEncoder_WriteContext(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+
+	encoder := blockio.NewBlock16Encoder(c1, json.Marshal)
+
+	//
+	// Nobody reading: the encoder remains usable
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := encoder.WriteContext(ctx, "data")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, blockio.ErrInterrupted)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		var v string
+		decoder := blockio.NewBlock16Decoder(c2, json.Unmarshal)
+		assert.NoError(t, decoder.Read(&v))
+		assert.Equal(t, "data", v)
+	}()
+	assert.NoError(t, encoder.WriteContext(context.Background(), "data"))
+	<-done
+
+	//
+	// Interrupted in the middle of a block: sticky error
+
+	go func() {
+		c2.Read(make([]byte, 3))
+	}()
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	err = encoder.WriteContext(ctx, "data")
+	assert.ErrorIs(t, err, blockio.ErrInterrupted)
+	assert.ErrorIs(t, encoder.Write("data"), blockio.ErrInterrupted)
+}
+
+func TestContext_Conn(t *testing.T) {
+	c1, c2 := net.Pipe()
+	client, err := blockio.NewConn(c1, blockio.ConnConfig{})
+	assert.NoError(t, err)
+	server, err := blockio.NewConn(c2, blockio.ConnConfig{})
+	assert.NoError(t, err)
+	defer client.Close()
+	defer server.Close()
+
+	encoder := blockio.NewBlockEncoder(client, json.Marshal)
+	decoder := blockio.NewBlockDecoder(server, json.Unmarshal, make([]byte, blockio.DefaultMaxMessageSize))
+	var v string
+
+	//
+	// Nothing received: the decoder remains usable
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = decoder.ReadContext(ctx, &v)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, blockio.ErrInterrupted)
+
+	go func() {
+		assert.NoError(t, client.Encode("data"))
+	}()
+	assert.NoError(t, decoder.ReadContext(context.Background(), &v))
+	assert.Equal(t, "data", v)
+
+	//
+	// The deadline set on the connection is kept when ctx has none
+
+	assert.NoError(t, server.SetReadDeadline(time.Now().Add(10*time.Millisecond)))
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	err = decoder.ReadContext(ctx, &v)
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.NotErrorIs(t, err, blockio.ErrInterrupted)
+	assert.NoError(t, server.SetReadDeadline(time.Time{}))
+
+	//
+	// Nobody reading: the encoder remains usable
+
+	ctx, cancel = context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = encoder.WriteContext(ctx, "datum")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.NotErrorIs(t, err, blockio.ErrInterrupted)
+
+	go func() {
+		assert.NoError(t, encoder.WriteContext(context.Background(), "datum"))
+	}()
+	assert.NoError(t, decoder.ReadContext(context.Background(), &v))
+	assert.Equal(t, "datum", v)
+}
@@ -0,0 +1,213 @@
+package blockio
+
+import (
+	"bytes"
+	"compress/flate"
+	"errors"
+	"io"
+	"sort"
+)
+
+// MaxDictionary is the maximum size in bytes of a compression dictionary (the flate window).
+const MaxDictionary = 32 << 10
+
+var (
+	// ErrInvalidHeader is returned when a stream does not start with the expected header.
+	ErrInvalidHeader = errors.New("invalid stream header")
+	// ErrDictionaryTooLarge is returned when a dictionary exceeds MaxDictionary.
+	ErrDictionaryTooLarge = errors.New("dictionary too large")
+)
+
+var dictMagic = []byte("BIOD")
+
+type dictWriter struct {
+	dst    io.Writer
+	dict   []byte
+	header bool
+	buf    bytes.Buffer
+	fw     *flate.Writer
+}
+
+// NewDictWriter returns a new writer that compresses each block against dict at the given level before writing it to w.
+// Provided w must be a block writer.
+// The dictionary is written once in a header block before the first block.
+func NewDictWriter(w io.Writer, dict []byte, level int) (io.Writer, error) {
+	if len(dict) > MaxDictionary {
+		return nil, ErrDictionaryTooLarge
+	}
+
+	dw := &dictWriter{
+		dst:  w,
+		dict: dict,
+	}
+
+	var err error
+	dw.fw, err = flate.NewWriterDict(&dw.buf, level, dict)
+	if err != nil {
+		return nil, err
+	}
+
+	return dw, nil
+}
+
+func (w *dictWriter) Write(block []byte) (n int, err error) {
+	if !w.header {
+		header := append(append([]byte(nil), dictMagic...), w.dict...)
+		if _, err = w.dst.Write(header); err != nil {
+			return 0, err
+		}
+		w.header = true
+	}
+
+	w.buf.Reset()
+	w.buf.WriteByte(byte(CompressionFlate))
+	w.fw.Reset(&w.buf) // Keeps the dictionary.
+	if _, err = w.fw.Write(block); err != nil {
+		return 0, err
+	}
+	if err = w.fw.Close(); err != nil {
+		return 0, err
+	}
+
+	if w.buf.Len() > len(block)+1 {
+		// Compression does not help, store as is.
+		w.buf.Reset()
+		w.buf.WriteByte(byte(CompressionNone))
+		w.buf.Write(block)
+	}
+
+	if _, err = w.dst.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(block), nil
+}
+
+type dictReader struct {
+	src  io.Reader
+	buf  []byte
+	max  int
+	dict []byte
+	br   bytes.Reader
+	fr   io.ReadCloser
+}
+
+// NewDictReader returns a new reader that decompresses blocks of size up to size read from r,
+// using the dictionary read from the header block of the stream.
+// Provided r must be a block reader.
+// Blocks decompressing to more than max bytes are rejected with ErrDecompressedTooLarge
+// and the buffer given to Read must be able to hold max bytes.
+func NewDictReader(r io.Reader, size, max int) io.Reader {
+	return &dictReader{
+		src: r,
+		buf: make([]byte, size),
+		max: max,
+	}
+}
+
+// Dictionary returns the dictionary read from the header of r, if any.
+// r must be a reader returned by NewDictReader.
+func Dictionary(r io.Reader) []byte {
+	if dr, ok := r.(*dictReader); ok {
+		return dr.dict
+	}
+	return nil
+}
+
+func (r *dictReader) Read(p []byte) (n int, err error) {
+	if cap(p) < r.max {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	if r.fr == nil {
+		n, err = r.src.Read(r.buf[:cap(r.buf)])
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.HasPrefix(r.buf[:n], dictMagic) || n-len(dictMagic) > MaxDictionary {
+			return 0, ErrInvalidHeader
+		}
+
+		r.dict = append([]byte(nil), r.buf[len(dictMagic):n]...)
+		r.fr = flate.NewReaderDict(&r.br, r.dict)
+	}
+
+	n, err = r.src.Read(r.buf[:cap(r.buf)])
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+
+	if r.buf[0]&^compressionMask != 0 {
+		return 0, ErrUnknownCompression // No flag is defined yet.
+	}
+	data := r.buf[1:n]
+	switch Compression(r.buf[0]) {
+	case CompressionNone:
+		if len(data) > r.max {
+			return 0, ErrDecompressedTooLarge
+		}
+		return copy(p[:r.max], data), nil
+	case CompressionFlate:
+		r.br.Reset(data)
+		if err = r.fr.(flate.Resetter).Reset(&r.br, r.dict); err != nil {
+			return 0, err
+		}
+		return readDecompressed(r.fr, p[:r.max])
+	default:
+		return 0, ErrUnknownCompression
+	}
+}
+
+// TrainDictionary builds a dictionary of at most size bytes from samples.
+// It keeps the most frequent substrings of the samples, the most frequent ones at the end
+// of the dictionary where flate references them with the shortest distances.
+func TrainDictionary(samples [][]byte, size int) []byte {
+	const gram = 8
+
+	if size > MaxDictionary {
+		size = MaxDictionary
+	}
+
+	counts := map[string]int{}
+	for _, sample := range samples {
+		for i := 0; i+gram <= len(sample); i++ {
+			counts[string(sample[i:i+gram])]++
+		}
+	}
+
+	grams := make([]string, 0, len(counts))
+	for g, c := range counts {
+		if c > 1 {
+			grams = append(grams, g)
+		}
+	}
+	sort.Slice(grams, func(i, j int) bool {
+		if counts[grams[i]] != counts[grams[j]] {
+			return counts[grams[i]] > counts[grams[j]]
+		}
+		return grams[i] < grams[j]
+	})
+
+	var selected []string
+	var dict []byte
+	for _, g := range grams {
+		if len(dict)+gram > size {
+			break
+		}
+		if bytes.Contains(dict, []byte(g)) {
+			continue
+		}
+
+		selected = append(selected, g)
+		dict = append(dict, g...)
+	}
+
+	// Most frequent last.
+	dict = dict[:0]
+	for i := len(selected) - 1; i >= 0; i-- {
+		dict = append(dict, selected[i]...)
+	}
+	return dict
+}
@@ -0,0 +1,111 @@
+package blockio_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDictWriter_Write(t *testing.T) {
+	var samples [][]byte
+	for i := 0; i < 100; i++ {
+		samples = append(samples, []byte(fmt.Sprintf(`{"identifier":%d,"description":"a record","enabled":true}`, i)))
+	}
+	dict := blockio.TrainDictionary(samples, 1024)
+	assert.NotEmpty(t, dict)
+	assert.LessOrEqual(t, len(dict), 1024)
+
+	//
+	// Compression against the dictionary
+
+	var plain, compressed bytes.Buffer
+
+	w, err := blockio.NewCompressWriter(blockio.NewWriter16(&plain), blockio.CompressionFlate, flate.BestCompression)
+	assert.NoError(t, err)
+	dw, err := blockio.NewDictWriter(blockio.NewWriter16(&compressed), dict, flate.BestCompression)
+	assert.NoError(t, err)
+
+	for _, sample := range samples {
+		_, err = w.Write(sample)
+		assert.NoError(t, err)
+		_, err = dw.Write(sample)
+		assert.NoError(t, err)
+	}
+	assert.Less(t, compressed.Len(), plain.Len())
+
+	//
+	// Decompression
+
+	r := blockio.NewDictReader(blockio.NewReader16(&compressed), blockio.MaxBlock16, 1024)
+	block := make([]byte, 1024)
+	for _, sample := range samples {
+		n, err := r.Read(block)
+		assert.NoError(t, err)
+		assert.Equal(t, sample, block[:n])
+	}
+	assert.Equal(t, dict, blockio.Dictionary(r))
+
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.EOF)
+
+	//
+	// Errors
+
+	_, err = blockio.NewDictWriter(io.Discard, make([]byte, blockio.MaxDictionary+1), flate.BestSpeed)
+	assert.ErrorIs(t, err, blockio.ErrDictionaryTooLarge)
+
+	r = blockio.NewDictReader(blockio.NewReader8(bytes.NewReader([]byte{4, 'd', 'a', 't', 'a'})), blockio.MaxBlock8, blockio.MaxBlock8)
+	_, err = r.Read(block[:blockio.MaxBlock8])
+	assert.ErrorIs(t, err, blockio.ErrInvalidHeader)
+
+	r = blockio.NewDictReader(blockio.NewReader8(bytes.NewReader([]byte{4, 'B', 'I', 'O', 'D', 2, 0x10, 'a'})), blockio.MaxBlock8, blockio.MaxBlock8)
+	_, err = r.Read(block[:blockio.MaxBlock8])
+	assert.ErrorIs(t, err, blockio.ErrUnknownCompression) // Reserved flags.
+
+	//
+	// Truncated block
+
+	compressed.Reset()
+	dw, err = blockio.NewDictWriter(blockio.NewWriter16(&compressed), dict, flate.BestCompression)
+	assert.NoError(t, err)
+	_, err = dw.Write(bytes.Repeat(samples[0], 10))
+	assert.NoError(t, err)
+
+	header := make([]byte, blockio.MaxBlock16)
+	hr := blockio.NewReader16(&compressed)
+	n, err := hr.Read(header)
+	assert.NoError(t, err)
+
+	var truncated bytes.Buffer
+	_, err = blockio.NewWriter16(&truncated).Write(header[:n])
+	assert.NoError(t, err)
+	truncated.Write(truncateBlock16(t, compressed.Bytes(), 3))
+
+	r = blockio.NewDictReader(blockio.NewReader16(&truncated), blockio.MaxBlock16, 1024)
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
+
+func TestDictWriter_Encoder(t *testing.T) {
+	var buf bytes.Buffer
+	dw, err := blockio.NewDictWriter(blockio.NewWriter16(&buf), []byte(`"value-`), flate.BestSpeed)
+	assert.NoError(t, err)
+
+	encoder := blockio.NewBlockEncoder(dw, json.Marshal)
+	assert.NoError(t, encoder.Write("value-1"))
+	assert.NoError(t, encoder.Write("value-2"))
+
+	decoder := blockio.NewBlockDecoder(blockio.NewDictReader(blockio.NewReader16(&buf), blockio.MaxBlock16, blockio.MaxBlock16), json.Unmarshal, make([]byte, blockio.MaxBlock16))
+
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "value-1", v)
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "value-2", v)
+}
@@ -0,0 +1,137 @@
+package blockio
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrInvalidFrame is returned when a block is too short to be a typed frame.
+var ErrInvalidFrame = errors.New("invalid typed frame")
+
+// An UnknownTypeError is returned when no handler is registered for the type of a frame.
+type UnknownTypeError struct {
+	Type byte
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown frame type %d", e.Type)
+}
+
+////////////////////////////
+//                        //
+// FrameWriter            //
+//                        //
+////////////////////////////
+
+// A FrameWriter writes typed frames ([size][type][data]) as blocks.
+type FrameWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewFrameWriter returns a new FrameWriter writing to w.
+// Provided w must be a block writer.
+func NewFrameWriter(w io.Writer) *FrameWriter {
+	return &FrameWriter{w: w}
+}
+
+// Write writes data in a frame of the given type.
+func (w *FrameWriter) Write(typ byte, data []byte) error {
+	w.buf = append(w.buf[:0], typ)
+	w.buf = append(w.buf, data...)
+
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// Encode writes v encoded with h in a frame of the given type.
+func (w *FrameWriter) Encode(typ byte, h Encode, v any) error {
+	data, err := h(v)
+	if err != nil {
+		return err
+	}
+
+	return w.Write(typ, data)
+}
+
+////////////////////////////
+//                        //
+// TypedDispatcher        //
+//                        //
+////////////////////////////
+
+// A TypedDispatcher reads typed frames and invokes the handler registered for their type.
+type TypedDispatcher struct {
+	r           io.Reader
+	buf         []byte
+	handlers    map[byte]func(data []byte) error
+	skipUnknown bool
+}
+
+// NewTypedDispatcher returns a new TypedDispatcher reading frames from r.
+// Provided r must be a block reader and buf must be large enough to handle blocks.
+func NewTypedDispatcher(r io.Reader, buf []byte) *TypedDispatcher {
+	return &TypedDispatcher{
+		r:        r,
+		buf:      buf,
+		handlers: map[byte]func(data []byte) error{},
+	}
+}
+
+// Handle registers fn for the frames of the given type.
+// The data given to fn is only valid until fn returns.
+func (d *TypedDispatcher) Handle(typ byte, fn func(data []byte) error) {
+	d.handlers[typ] = fn
+}
+
+// HandleType registers fn for the frames of the given type, decoded with h in a new T.
+func HandleType[T any](d *TypedDispatcher, typ byte, h Decode, fn func(v *T) error) {
+	d.Handle(typ, func(data []byte) error {
+		v := new(T)
+		if err := h(data, v); err != nil {
+			return err
+		}
+		return fn(v)
+	})
+}
+
+// SkipUnknown sets whether frames without handler are skipped instead of returning an *UnknownTypeError.
+func (d *TypedDispatcher) SkipUnknown(skip bool) {
+	d.skipUnknown = skip
+}
+
+// Dispatch reads one frame and invokes its handler.
+func (d *TypedDispatcher) Dispatch() error {
+	n, err := d.r.Read(d.buf[:cap(d.buf)])
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrInvalidFrame
+	}
+
+	typ := d.buf[0]
+	fn, ok := d.handlers[typ]
+	if !ok {
+		if d.skipUnknown {
+			return nil
+		}
+		return &UnknownTypeError{Type: typ}
+	}
+
+	return fn(d.buf[1:n])
+}
+
+// Run dispatches all the frames until io.EOF or the first error.
+func (d *TypedDispatcher) Run() error {
+	for {
+		err := d.Dispatch()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
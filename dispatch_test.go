@@ -0,0 +1,79 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedDispatcher_Run(t *testing.T) {
+	const (
+		typeCreated byte = iota + 1
+		typeDeleted
+		typeUnknown
+	)
+
+	type created struct {
+		Name string
+	}
+
+	var buf bytes.Buffer
+	w := blockio.NewFrameWriter(blockio.NewWriter16(&buf))
+	assert.NoError(t, w.Encode(typeCreated, json.Marshal, created{Name: "test"}))
+	assert.NoError(t, w.Write(typeDeleted, []byte("42")))
+	assert.NoError(t, w.Write(typeUnknown, []byte("?")))
+	assert.NoError(t, w.Write(typeDeleted, []byte("24")))
+	assert.Equal(t, []byte{0, 3, typeDeleted, '4', '2'}, buf.Bytes()[18:23])
+	data := buf.Bytes()
+
+	var events []string
+	newDispatcher := func() *blockio.TypedDispatcher {
+		d := blockio.NewTypedDispatcher(blockio.NewReader16(bytes.NewReader(data)), make([]byte, blockio.MaxBlock16))
+		blockio.HandleType(d, typeCreated, json.Unmarshal, func(v *created) error {
+			events = append(events, "created "+v.Name)
+			return nil
+		})
+		d.Handle(typeDeleted, func(data []byte) error {
+			events = append(events, "deleted "+string(data))
+			return nil
+		})
+		return d
+	}
+
+	//
+	// Unknown type
+
+	d := newDispatcher()
+	err := d.Run()
+	var uerr *blockio.UnknownTypeError
+	if assert.True(t, errors.As(err, &uerr)) {
+		assert.Equal(t, typeUnknown, uerr.Type)
+	}
+	assert.Equal(t, []string{"created test", "deleted 42"}, events)
+
+	//
+	// Skipped unknown type
+
+	events = nil
+	d = newDispatcher()
+	d.SkipUnknown(true)
+	assert.NoError(t, d.Run())
+	assert.Equal(t, []string{"created test", "deleted 42", "deleted 24"}, events)
+
+	//
+	// Handler error
+
+	d = newDispatcher()
+	d.Handle(typeCreated, func([]byte) error { return errors.New("handler") })
+	assert.EqualError(t, d.Run(), "handler")
+
+	//
+	// Empty frame
+
+	d = blockio.NewTypedDispatcher(blockio.NewReader16(bytes.NewReader([]byte{0, 0, 0, 1, typeCreated})), make([]byte, blockio.MaxBlock16))
+	assert.ErrorIs(t, d.Dispatch(), blockio.ErrInvalidFrame)
+}
@@ -0,0 +1,212 @@
+package blockio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+type (
+	// A Syncer commits written data to stable storage (e.g. *os.File).
+	Syncer interface {
+		Sync() error
+	}
+
+	// A Commit describes where a block has been written by a GroupEncoder.
+	Commit struct {
+		// Offset is the position in bytes of the block (header included) in the destination.
+		Offset int64
+		// Ordinal is the zero-based index of the block in the destination.
+		Ordinal uint64
+	}
+
+	// A GroupEncoder encodes objects and writes them as blocks.
+	// It is safe for concurrent use: pending blocks from all callers are coalesced
+	// into one write and one sync of the underlying writer (group commit).
+	GroupEncoder struct {
+		dst    io.Writer
+		encode Encode
+
+		mu       sync.Mutex
+		pending  []*groupRequest
+		flushing bool
+		err      error // Sticky error of the underlying writer.
+
+		batch   bytes.Buffer
+		bw      io.Writer // Block writer over batch.
+		offset  int64
+		ordinal uint64
+	}
+
+	groupRequest struct {
+		payload []byte
+		commit  Commit
+		err     error
+		done    chan struct{}
+		lead    chan struct{} // Signaled when the request is handed the leadership.
+	}
+)
+
+// NewGroupEncoder encodes values to dst using the given h.
+// Blocks are framed using the block writer returned by nw (e.g. NewWriter16).
+// If dst implements Syncer, it is synced after each batch.
+func NewGroupEncoder(dst io.Writer, h Encode, nw func(io.Writer) io.Writer) *GroupEncoder {
+	e := &GroupEncoder{
+		dst:    dst,
+		encode: h,
+	}
+	e.bw = nw(&e.batch)
+
+	return e
+}
+
+// NewGroup8Encoder encodes values to dst using the given h in Block8.
+func NewGroup8Encoder(dst io.Writer, h Encode) *GroupEncoder {
+	return NewGroupEncoder(dst, h, NewWriter8)
+}
+
+// NewGroup16Encoder encodes values to dst using the given h in Block16.
+func NewGroup16Encoder(dst io.Writer, h Encode) *GroupEncoder {
+	return NewGroupEncoder(dst, h, NewWriter16)
+}
+
+// NewGroup24Encoder encodes values to dst using the given h in Block24.
+func NewGroup24Encoder(dst io.Writer, h Encode) *GroupEncoder {
+	return NewGroupEncoder(dst, h, NewWriter24)
+}
+
+// NewGroup32Encoder encodes values to dst using the given h in Block32.
+func NewGroup32Encoder(dst io.Writer, h Encode) *GroupEncoder {
+	return NewGroupEncoder(dst, h, NewWriter32)
+}
+
+// SetOffset sets the offset and ordinal of the next written block.
+// It is useful when appending to an existing file and must be called before any Write.
+func (e *GroupEncoder) SetOffset(offset int64, ordinal uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.offset = offset
+	e.ordinal = ordinal
+}
+
+// Write encodes v and blocks until its block is written and synced to the underlying writer.
+// It returns where the block has been written.
+//
+// Once the underlying writer failed, all subsequent writes return the same error.
+func (e *GroupEncoder) Write(v any) (Commit, error) {
+	payload, err := e.encode(v)
+	if err != nil {
+		return Commit{}, err
+	}
+
+	req := &groupRequest{
+		payload: payload,
+		done:    make(chan struct{}),
+		lead:    make(chan struct{}, 1),
+	}
+
+	e.mu.Lock()
+	if e.err != nil {
+		err = e.err
+		e.mu.Unlock()
+		return Commit{}, err
+	}
+
+	e.pending = append(e.pending, req)
+	if e.flushing {
+		// Another caller is the leader, it will either commit our request or hand us the leadership.
+		e.mu.Unlock()
+		select {
+		case <-req.done:
+			return req.commit, req.err
+		case <-req.lead:
+		}
+		e.mu.Lock()
+	}
+
+	// Become the leader for the pending requests, ours included.
+	e.flushing = true
+	batch := e.pending
+	e.pending = nil
+	e.mu.Unlock()
+
+	e.commit(batch)
+
+	e.mu.Lock()
+	if len(e.pending) > 0 {
+		e.pending[0].lead <- struct{}{} // Hand the leadership to the next waiting request.
+	} else {
+		e.flushing = false
+	}
+	e.mu.Unlock()
+
+	return req.commit, req.err
+}
+
+// commit writes and syncs the given batch.
+// Only the leader calls it, so the batch buffer and positions are not shared.
+func (e *GroupEncoder) commit(batch []*groupRequest) {
+	defer func() {
+		for _, req := range batch {
+			close(req.done)
+		}
+	}()
+
+	e.mu.Lock()
+	err := e.err
+	e.mu.Unlock()
+	if err != nil {
+		for _, req := range batch {
+			req.err = err
+		}
+		return
+	}
+
+	e.batch.Reset()
+	offset := e.offset
+	ordinal := e.ordinal
+	var committed []*groupRequest
+
+	for _, req := range batch {
+		start := e.batch.Len()
+		if _, err := e.bw.Write(req.payload); err != nil {
+			req.err = err // Only this block is rejected (e.g. ErrBlockSize).
+			continue
+		}
+
+		req.commit = Commit{
+			Offset:  offset,
+			Ordinal: ordinal,
+		}
+		offset += int64(e.batch.Len() - start)
+		ordinal++
+		committed = append(committed, req)
+	}
+
+	if len(committed) == 0 {
+		return
+	}
+
+	_, err = e.dst.Write(e.batch.Bytes())
+	if err == nil {
+		if s, ok := e.dst.(Syncer); ok {
+			err = s.Sync()
+		}
+	}
+
+	if err != nil {
+		e.mu.Lock()
+		e.err = err
+		e.mu.Unlock()
+
+		for _, req := range committed {
+			req.commit = Commit{}
+			req.err = err
+		}
+		return
+	}
+
+	e.offset = offset
+	e.ordinal = ordinal
+}
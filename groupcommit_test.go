@@ -0,0 +1,149 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+type syncBuffer struct {
+	bytes.Buffer
+	writes int
+	syncs  int
+	err    error
+
+	syncing chan struct{} // Signaled on each Sync when hold is set.
+	hold    chan struct{} // Blocks Sync until closed.
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	b.writes++
+	return b.Buffer.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	b.syncs++
+	if b.hold != nil {
+		b.syncing <- struct{}{}
+		<-b.hold
+	}
+	return nil
+}
+
+func TestGroupEncoder_Write(t *testing.T) {
+	var buf syncBuffer
+	encoder := blockio.NewGroup16Encoder(&buf, json.Marshal)
+
+	const n = 64
+	commits := make([]blockio.Commit, n)
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			c, err := encoder.Write(fmt.Sprintf("value-%02d", i))
+			assert.NoError(t, err)
+			commits[i] = c
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, buf.writes, buf.syncs)
+
+	//
+	// Each commit points to its own block
+
+	data := buf.Bytes()
+	sort.Slice(commits, func(i, j int) bool { return commits[i].Ordinal < commits[j].Ordinal })
+	for i, c := range commits {
+		assert.Equal(t, uint64(i), c.Ordinal)
+
+		var v string
+		decoder := blockio.NewBlock16Decoder(bytes.NewReader(data[c.Offset:]), json.Unmarshal)
+		assert.NoError(t, decoder.Read(&v))
+		assert.Len(t, v, len("value-00"))
+	}
+
+	//
+	// Oversized block only fails its own caller
+
+	_, err := encoder.Write(string(bytes.Repeat([]byte{'a'}, blockio.MaxBlock16)))
+	assert.ErrorIs(t, err, blockio.ErrBlockSize)
+
+	c, err := encoder.Write("last")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(n), c.Ordinal)
+	assert.Equal(t, int64(len(data)), c.Offset)
+}
+
+func TestGroupEncoder_Coalesce(t *testing.T) {
+	const n = 16
+	buf := syncBuffer{
+		syncing: make(chan struct{}, n+1),
+		hold:    make(chan struct{}),
+	}
+	encoder := blockio.NewGroup16Encoder(&buf, json.Marshal)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		_, err := encoder.Write("first")
+		assert.NoError(t, err)
+	}()
+	<-buf.syncing // The first batch is held open.
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			_, err := encoder.Write(fmt.Sprintf("value-%02d", i))
+			assert.NoError(t, err)
+		}(i)
+	}
+	time.Sleep(50 * time.Millisecond) // Let the writes queue behind the first batch.
+
+	close(buf.hold)
+	wg.Wait()
+
+	assert.Equal(t, 2, buf.writes)
+	assert.Equal(t, 2, buf.syncs)
+}
+
+func TestGroupEncoder_WriteError(t *testing.T) {
+	buf := syncBuffer{err: io.ErrShortWrite}
+	encoder := blockio.NewGroup8Encoder(&buf, json.Marshal)
+
+	_, err := encoder.Write("data")
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+
+	//
+	// Sticky error
+
+	buf.err = nil
+	_, err = encoder.Write("data")
+	assert.ErrorIs(t, err, io.ErrShortWrite)
+
+	//
+	// Encode error is not sticky
+
+	encoder = blockio.NewGroup8Encoder(&buf, func(any) ([]byte, error) { return nil, errors.New("encode") })
+	_, err = encoder.Write("data")
+	assert.EqualError(t, err, "encode")
+}
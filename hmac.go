@@ -0,0 +1,115 @@
+package blockio
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+// Bounds of HMAC tag length in bytes.
+const (
+	MinHMACTag = 4
+	MaxHMACTag = sha256.Size
+)
+
+// ErrTagSize is returned when a tag length is out of bounds.
+var ErrTagSize = errors.New("invalid tag size")
+
+type hmacWriter struct {
+	dst     io.Writer
+	mac     hash.Hash
+	size    int
+	ordinal uint64
+	buf     []byte
+}
+
+// NewHMACWriter returns a new writer that appends to each block an HMAC-SHA256 tag truncated to size bytes
+// before writing it to w. Provided w must be a block writer.
+// The tag covers the block data and its ordinal so blocks cannot be modified or reordered without being detected.
+func NewHMACWriter(w io.Writer, secret []byte, size int) (io.Writer, error) {
+	if size < MinHMACTag || size > MaxHMACTag {
+		return nil, ErrTagSize
+	}
+
+	return &hmacWriter{
+		dst:  w,
+		mac:  hmac.New(sha256.New, secret),
+		size: size,
+	}, nil
+}
+
+func (w *hmacWriter) Write(block []byte) (n int, err error) {
+	w.buf = append(w.buf[:0], block...)
+	w.buf = blockMAC(w.mac, w.buf, w.ordinal, block)[:len(block)+w.size]
+
+	if _, err = w.dst.Write(w.buf); err != nil {
+		return 0, err
+	}
+
+	w.ordinal++
+	return len(block), nil
+}
+
+// blockMAC appends to dst the MAC of the ordinal and data.
+func blockMAC(mac hash.Hash, dst []byte, ordinal uint64, data []byte) []byte {
+	var o [8]byte
+	binary.BigEndian.PutUint64(o[:], ordinal)
+
+	mac.Reset()
+	mac.Write(o[:])
+	mac.Write(data)
+	return mac.Sum(dst)
+}
+
+type hmacReader struct {
+	src     io.Reader
+	mac     hash.Hash
+	size    int
+	buf     []byte
+	sum     []byte
+	ordinal uint64
+}
+
+// NewHMACReader returns a new reader that verifies and strips the tag of blocks of size up to size
+// written by an HMAC writer to r with the same secret and tag size.
+// Provided r must be a block reader.
+// Invalid blocks are reported by a *BlockError wrapping ErrAuthentication.
+func NewHMACReader(r io.Reader, size int, secret []byte, tagSize int) (io.Reader, error) {
+	if tagSize < MinHMACTag || tagSize > MaxHMACTag {
+		return nil, ErrTagSize
+	}
+
+	return &hmacReader{
+		src:  r,
+		mac:  hmac.New(sha256.New, secret),
+		size: tagSize,
+		buf:  make([]byte, size),
+	}, nil
+}
+
+func (r *hmacReader) Read(p []byte) (n int, err error) {
+	n, err = r.src.Read(r.buf[:cap(r.buf)])
+	if err != nil {
+		return 0, err
+	}
+	if n < r.size {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrAuthentication}
+	}
+
+	data := r.buf[:n-r.size]
+	tag := r.buf[n-r.size : n]
+	if len(data) > cap(p) {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	r.sum = blockMAC(r.mac, r.sum[:0], r.ordinal, data)
+	if !hmac.Equal(tag, r.sum[:r.size]) {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrAuthentication}
+	}
+
+	r.ordinal++
+	return copy(p[:cap(p)], data), nil
+}
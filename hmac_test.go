@@ -0,0 +1,81 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHMACWriter_Write(t *testing.T) {
+	secret := []byte("secret")
+
+	var buf bytes.Buffer
+	w, err := blockio.NewHMACWriter(blockio.NewWriter8(&buf), secret, 8)
+	assert.NoError(t, err)
+
+	for _, block := range []string{"data", "datum", "last"} {
+		n, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+		assert.Equal(t, len(block), n)
+	}
+	data := buf.Bytes()
+	assert.Equal(t, []byte{12, 'd', 'a', 't', 'a'}, data[:5]) // Readable data followed by the tag.
+
+	r, err := blockio.NewHMACReader(blockio.NewReader8(bytes.NewReader(data)), blockio.MaxBlock8, secret, 8)
+	assert.NoError(t, err)
+	blocks, err := readAll(r, blockio.MaxBlock8)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "datum", "last"}, blocks)
+
+	//
+	// Wrong secret
+
+	r, err = blockio.NewHMACReader(blockio.NewReader8(bytes.NewReader(data)), blockio.MaxBlock8, []byte("terces"), 8)
+	assert.NoError(t, err)
+	_, err = readAll(r, blockio.MaxBlock8)
+	assert.ErrorIs(t, err, blockio.ErrAuthentication)
+
+	//
+	// Tampered second block
+
+	tampered := append([]byte(nil), data...)
+	tampered[14] = 'o' // datum -> dotum
+	r, err = blockio.NewHMACReader(blockio.NewReader8(bytes.NewReader(tampered)), blockio.MaxBlock8, secret, 8)
+	assert.NoError(t, err)
+	blocks, err = readAll(r, blockio.MaxBlock8)
+	assert.Equal(t, []string{"data"}, blocks)
+
+	var berr *blockio.BlockError
+	assert.True(t, errors.As(err, &berr))
+	assert.Equal(t, uint64(1), berr.Index)
+	assert.ErrorIs(t, err, blockio.ErrAuthentication)
+
+	//
+	// Tag size
+
+	_, err = blockio.NewHMACWriter(&buf, secret, blockio.MinHMACTag-1)
+	assert.ErrorIs(t, err, blockio.ErrTagSize)
+	_, err = blockio.NewHMACReader(&buf, blockio.MaxBlock8, secret, blockio.MaxHMACTag+1)
+	assert.ErrorIs(t, err, blockio.ErrTagSize)
+}
+
+func TestHMAC_Encoder(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := blockio.NewHMACWriter(blockio.NewWriter16(&buf), []byte("secret"), blockio.MaxHMACTag)
+	assert.NoError(t, err)
+
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write("audit"))
+
+	r, err := blockio.NewHMACReader(blockio.NewReader16(&buf), blockio.MaxBlock16, []byte("secret"), blockio.MaxHMACTag)
+	assert.NoError(t, err)
+
+	decoder := blockio.NewBlockDecoder(r, json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "audit", v)
+}
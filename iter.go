@@ -0,0 +1,43 @@
+//go:build go1.23
+
+package blockio
+
+import (
+	"errors"
+	"io"
+	"iter"
+)
+
+// Blocks returns a sequence over the raw data of all the remaining blocks.
+// The sequence stops at io.EOF; any other error is yielded once as the last element.
+// The yielded slices are reused by the next iteration and must be copied to be retained.
+func (d *Decoder) Blocks() iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for {
+			data, err := d.ReadBlock()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(data, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// All returns a sequence over the values of all the remaining blocks.
+// The sequence stops at io.EOF; any other error is yielded once as the last element.
+// Each yielded value is freshly decoded and can be retained.
+func (d *TypedDecoder[T]) All() iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			v, err := d.Read()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if !yield(v, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+//go:build go1.23
+
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecoder_Blocks(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{4, 'd', 'a', 't', 'a', 5, 'd', 'a', 't', 'u', 'm'})
+	decoder := blockio.NewBlock8Decoder(buf, json.Unmarshal)
+
+	var blocks []string
+	for data, err := range decoder.Blocks() {
+		assert.NoError(t, err)
+		blocks = append(blocks, string(data))
+	}
+	assert.Equal(t, []string{"data", "datum"}, blocks)
+}
+
+func TestTypedDecoder_All(t *testing.T) {
+	buf := bytes.NewBufferString("\x011\x012\x013")
+	decoder := blockio.NewTypedDecoder(blockio.NewReader8(buf), blockio.DecodeOf[int](json.Unmarshal), make([]byte, blockio.MaxBlock8))
+
+	var values []int
+	for v, err := range decoder.All() {
+		assert.NoError(t, err)
+		values = append(values, v)
+		if v == 2 {
+			break
+		}
+	}
+	assert.Equal(t, []int{1, 2}, values)
+
+	//
+	// Error is yielded once
+
+	buf = bytes.NewBufferString("\x011\x01a\x013")
+	decoder = blockio.NewTypedDecoder(blockio.NewReader8(buf), blockio.DecodeOf[int](json.Unmarshal), make([]byte, blockio.MaxBlock8))
+
+	var errs int
+	for _, err := range decoder.All() {
+		if err != nil {
+			errs++
+		}
+	}
+	assert.Equal(t, 1, errs)
+}
@@ -0,0 +1,155 @@
+package blockio
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const dataKeySize = 32 // AES-256.
+
+var (
+	// ErrUnknownKey is returned when a key identifier cannot be resolved.
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrKeyID is returned when a key identifier is empty or longer than 255 bytes.
+	ErrKeyID = errors.New("invalid key identifier")
+)
+
+type (
+	// A KeyProvider resolves the AEAD of a key identifier.
+	KeyProvider interface {
+		Key(id string) (cipher.AEAD, error)
+	}
+
+	// KeyProviderFunc is an adapter to allow the use of ordinary functions as KeyProvider.
+	KeyProviderFunc func(id string) (cipher.AEAD, error)
+
+	// A Keyring is a KeyProvider holding keys in memory.
+	Keyring map[string]cipher.AEAD
+)
+
+// Key calls f(id).
+func (f KeyProviderFunc) Key(id string) (cipher.AEAD, error) {
+	return f(id)
+}
+
+// Key returns the AEAD of id or ErrUnknownKey.
+func (k Keyring) Key(id string) (cipher.AEAD, error) {
+	aead, ok := k[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return aead, nil
+}
+
+// NewKeyedSealWriter is like NewSealWriter but records id in the header
+// so the key can be resolved by a KeyProvider when reading (see NewKeyedOpenReader).
+// Keys can be rotated without rewriting the existing streams.
+func NewKeyedSealWriter(w io.Writer, id string, aead cipher.AEAD) (io.WriteCloser, error) {
+	header, err := newKeyedSealHeader(sealVersionKeyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSealWriter(w, aead, header), nil
+}
+
+// NewEnvelopeSealWriter is like NewKeyedSealWriter but seals the blocks with a random data key (AES-256-GCM).
+// The data key is stored in the header, wrapped by the master key identified by id.
+func NewEnvelopeSealWriter(w io.Writer, id string, master cipher.AEAD) (io.WriteCloser, error) {
+	header, err := newKeyedSealHeader(sealVersionEnvelope, id)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, dataKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, master.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	wrapped := master.Seal(nonce, nonce, key, header) // The header so far is bound to the data key.
+	header = append(header, wrapped...)
+
+	return newSealWriter(w, aead, header), nil
+}
+
+// newKeyedSealHeader returns the beginning of a header with a random file ID and the key identifier.
+func newKeyedSealHeader(version byte, id string) ([]byte, error) {
+	if len(id) == 0 || len(id) > 0xFF {
+		return nil, ErrKeyID
+	}
+
+	header, err := newSealHeader(version)
+	if err != nil {
+		return nil, err
+	}
+
+	header = append(header, byte(len(id)))
+	return append(header, id...), nil
+}
+
+// NewKeyedOpenReader is like NewOpenReader but resolves the key recorded in the header
+// of streams written by NewKeyedSealWriter or NewEnvelopeSealWriter using keys.
+func NewKeyedOpenReader(r io.Reader, size int, keys KeyProvider) io.Reader {
+	return newOpenReader(r, size, func(header []byte) (cipher.AEAD, error) {
+		// magic | version | file ID | key ID length
+		offset := len(sealMagic) + 1 + sealFileID
+		if len(header) < offset+1 {
+			return nil, ErrInvalidHeader
+		}
+		version := header[len(sealMagic)]
+
+		l := int(header[offset])
+		offset++
+		if l == 0 || len(header) < offset+l {
+			return nil, ErrInvalidHeader
+		}
+		id := string(header[offset : offset+l])
+		offset += l
+
+		aead, err := keys.Key(id)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+
+		switch version {
+		case sealVersionKeyID:
+			if len(header) != offset {
+				return nil, ErrInvalidHeader
+			}
+			return aead, nil
+		case sealVersionEnvelope:
+			wrapped := header[offset:]
+			if len(wrapped) < aead.NonceSize() {
+				return nil, ErrInvalidHeader
+			}
+
+			key, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], header[:offset])
+			if err != nil {
+				return nil, &BlockError{Index: 0, Err: ErrAuthentication}
+			}
+			return newGCM(key)
+		default:
+			return nil, ErrInvalidHeader
+		}
+	})
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,93 @@
+package blockio_test
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedSealWriter_Write(t *testing.T) {
+	keys := blockio.Keyring{
+		"2023-01": newAEAD(t, 1),
+		"2023-02": newAEAD(t, 2),
+	}
+
+	for _, mode := range []string{"keyed", "envelope"} {
+		t.Run(mode, func(t *testing.T) {
+			// Key rotation: each stream uses the key of its period.
+			var streams [][]byte
+			for _, id := range []string{"2023-01", "2023-02"} {
+				var buf bytes.Buffer
+
+				var err error
+				var w interface {
+					Write([]byte) (int, error)
+					Close() error
+				}
+				if mode == "keyed" {
+					w, err = blockio.NewKeyedSealWriter(blockio.NewWriter16(&buf), id, keys[id])
+				} else {
+					w, err = blockio.NewEnvelopeSealWriter(blockio.NewWriter16(&buf), id, keys[id])
+				}
+				assert.NoError(t, err)
+
+				_, err = w.Write([]byte(id))
+				assert.NoError(t, err)
+				assert.NoError(t, w.Close())
+
+				assert.Contains(t, buf.String(), id) // Key ID in clear.
+				streams = append(streams, buf.Bytes())
+			}
+
+			for i, id := range []string{"2023-01", "2023-02"} {
+				r := blockio.NewKeyedOpenReader(blockio.NewReader16(bytes.NewReader(streams[i])), blockio.MaxBlock16, keys)
+				blocks, err := readAll(r, 64)
+				assert.NoError(t, err)
+				assert.Equal(t, []string{id}, blocks)
+			}
+
+			//
+			// Unknown key
+
+			r := blockio.NewKeyedOpenReader(blockio.NewReader16(bytes.NewReader(streams[0])), blockio.MaxBlock16, blockio.Keyring{})
+			_, err := readAll(r, 64)
+			assert.ErrorIs(t, err, blockio.ErrUnknownKey)
+
+			//
+			// Wrong key behind the ID
+
+			r = blockio.NewKeyedOpenReader(blockio.NewReader16(bytes.NewReader(streams[0])), blockio.MaxBlock16, blockio.KeyProviderFunc(func(id string) (cipher.AEAD, error) {
+				return keys["2023-02"], nil
+			}))
+			_, err = readAll(r, 64)
+			assert.ErrorIs(t, err, blockio.ErrAuthentication)
+		})
+	}
+
+	_, err := blockio.NewKeyedSealWriter(&bytes.Buffer{}, "", newAEAD(t, 1))
+	assert.ErrorIs(t, err, blockio.ErrKeyID)
+}
+
+func TestEnvelopeSealWriter_Header(t *testing.T) {
+	master := newAEAD(t, 1)
+
+	var buf bytes.Buffer
+	w, err := blockio.NewEnvelopeSealWriter(blockio.NewWriter16(&buf), "master", master)
+	assert.NoError(t, err)
+	_, err = w.Write([]byte("data"))
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	//
+	// Tampered wrapped data key
+
+	blocks := rawBlocks(t, buf.Bytes())
+	blocks[0][len(blocks[0])-1] ^= 0xFF
+
+	r := blockio.NewKeyedOpenReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks...))), blockio.MaxBlock16, blockio.Keyring{"master": master})
+	_, err = readAll(r, 64)
+	assert.ErrorIs(t, err, blockio.ErrAuthentication)
+}
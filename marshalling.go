@@ -19,6 +19,7 @@ type (
 		r      io.Reader
 		decode Decode
 		buf    []byte
+		err    error // Sticky error of an interrupted read.
 	}
 )
 
@@ -72,12 +73,27 @@ func NewBlock32CustomDecoder(r io.Reader, size int, h Decode) (*Decoder, error)
 
 // Read reads from its block reader and deserialized data in v.
 func (d *Decoder) Read(v any) error {
-	n, err := d.r.Read(d.buf[:cap(d.buf)])
+	data, err := d.ReadBlock()
 	if err != nil {
 		return err
 	}
 
-	return d.decode(d.buf[:n], v)
+	return d.decode(data, v)
+}
+
+// ReadBlock reads from its block reader and returns the raw data of the block.
+// The returned slice is only valid until the next read.
+func (d *Decoder) ReadBlock() ([]byte, error) {
+	if d.err != nil {
+		return nil, d.err
+	}
+
+	n, err := d.r.Read(d.buf[:cap(d.buf)])
+	if err != nil {
+		return nil, err
+	}
+
+	return d.buf[:n], nil
 }
 
 ////////////////////////////
@@ -94,6 +110,7 @@ type (
 	Encoder struct {
 		w      io.Writer
 		encode Encode
+		err    error // Sticky error of an interrupted write.
 	}
 )
 
@@ -128,6 +145,10 @@ func NewBlock32Encoder(w io.Writer, h Encode) *Encoder {
 
 // Write writes marshalized bytes to its writer of the given v.
 func (e *Encoder) Write(v any) error {
+	if e.err != nil {
+		return e.err
+	}
+
 	payload, err := e.encode(v)
 	if err != nil {
 		return err
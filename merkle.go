@@ -0,0 +1,345 @@
+package blockio
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+var (
+	// ErrInvalidFooter is returned when a stream does not end with the expected footer.
+	ErrInvalidFooter = errors.New("invalid stream footer")
+	// ErrRootMismatch is returned when the blocks of a stream do not match the Merkle root of its footer.
+	ErrRootMismatch = errors.New("merkle root mismatch")
+	// ErrIndexOutOfRange is returned when a block index is out of range of a Merkle tree.
+	ErrIndexOutOfRange = errors.New("block index out of range")
+)
+
+var merkleMagic = []byte("BIOM")
+
+const merkleWithTree = 0x01 // Footer flag set when the leaves are stored.
+
+////////////////////////////
+//                        //
+// MerkleTree             //
+//                        //
+////////////////////////////
+
+// A MerkleTree is a RFC 6962 Merkle tree over the hashes of blocks.
+type MerkleTree struct {
+	leaves [][sha256.Size]byte
+}
+
+// MerkleLeaf returns the leaf hash of the given block data.
+func MerkleLeaf(data []byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+
+	var leaf [sha256.Size]byte
+	h.Sum(leaf[:0])
+	return leaf
+}
+
+func merkleNode(left, right [sha256.Size]byte) [sha256.Size]byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left[:])
+	h.Write(right[:])
+
+	var node [sha256.Size]byte
+	h.Sum(node[:0])
+	return node
+}
+
+// NewMerkleTree returns a tree over the given leaf hashes.
+func NewMerkleTree(leaves [][sha256.Size]byte) *MerkleTree {
+	return &MerkleTree{leaves: leaves}
+}
+
+// Add appends the leaf of the given block data.
+func (t *MerkleTree) Add(data []byte) {
+	t.leaves = append(t.leaves, MerkleLeaf(data))
+}
+
+// Len returns the number of leaves.
+func (t *MerkleTree) Len() uint64 {
+	return uint64(len(t.leaves))
+}
+
+// Leaves returns the leaf hashes.
+func (t *MerkleTree) Leaves() [][sha256.Size]byte {
+	return t.leaves
+}
+
+// Root returns the root hash of the tree (the hash of an empty string for an empty tree).
+func (t *MerkleTree) Root() [sha256.Size]byte {
+	if len(t.leaves) == 0 {
+		return sha256.Sum256(nil)
+	}
+	return merkleRoot(t.leaves)
+}
+
+// Proof returns the inclusion proof (audit path) of the block i.
+func (t *MerkleTree) Proof(i uint64) ([][sha256.Size]byte, error) {
+	if i >= t.Len() {
+		return nil, ErrIndexOutOfRange
+	}
+	return merklePath(int(i), t.leaves), nil
+}
+
+func merkleRoot(leaves [][sha256.Size]byte) [sha256.Size]byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+
+	k := merkleSplit(len(leaves))
+	return merkleNode(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+func merklePath(m int, leaves [][sha256.Size]byte) [][sha256.Size]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+
+	k := merkleSplit(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+	return append(merklePath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+// merkleSplit returns the largest power of two smaller than n.
+func merkleSplit(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// VerifyProof reports whether data is the block i of a tree of count blocks with the given root.
+func VerifyProof(data []byte, i, count uint64, proof [][sha256.Size]byte, root [sha256.Size]byte) bool {
+	if i >= count {
+		return false
+	}
+
+	fn, sn := i, count-1
+	r := MerkleLeaf(data)
+	for _, p := range proof {
+		if sn == 0 {
+			return false
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = merkleNode(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = merkleNode(r, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	return sn == 0 && r == root
+}
+
+////////////////////////////
+//                        //
+// MerkleWriter           //
+//                        //
+////////////////////////////
+
+// A MerkleWriter writes blocks as is and builds a Merkle tree over them.
+// The root is written in a footer block on Close.
+type MerkleWriter struct {
+	dst      io.Writer
+	tree     MerkleTree
+	withTree bool
+	closed   bool
+}
+
+// NewMerkleWriter returns a new MerkleWriter writing blocks to w.
+// Provided w must be a block writer.
+// When withTree is true, the leaves are also stored in the footer so proofs can be produced without reading the blocks;
+// the footer must then fit in one block (32 bytes per block).
+func NewMerkleWriter(w io.Writer, withTree bool) *MerkleWriter {
+	return &MerkleWriter{
+		dst:      w,
+		withTree: withTree,
+	}
+}
+
+func (w *MerkleWriter) Write(block []byte) (n int, err error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if _, err = w.dst.Write(block); err != nil {
+		return 0, err
+	}
+
+	w.tree.Add(block)
+	return len(block), nil
+}
+
+// Tree returns the tree of the written blocks.
+func (w *MerkleWriter) Tree() *MerkleTree {
+	return &w.tree
+}
+
+// Close writes the footer. It does not close the underlying writer.
+func (w *MerkleWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	_, err := w.dst.Write(w.footer().marshal())
+	return err
+}
+
+func (w *MerkleWriter) footer() *MerkleFooter {
+	f := &MerkleFooter{
+		Count: w.tree.Len(),
+		Root:  w.tree.Root(),
+	}
+	if w.withTree {
+		f.Leaves = w.tree.leaves
+	}
+	return f
+}
+
+////////////////////////////
+//                        //
+// MerkleFooter           //
+//                        //
+////////////////////////////
+
+// A MerkleFooter is the last block of a stream written by a MerkleWriter.
+type MerkleFooter struct {
+	Count  uint64
+	Root   [sha256.Size]byte
+	Leaves [][sha256.Size]byte // Only when written with the tree.
+}
+
+// ParseMerkleFooter parses the data of the footer block.
+func ParseMerkleFooter(data []byte) (*MerkleFooter, error) {
+	hsize := len(merkleMagic) + 1 + 8 + sha256.Size
+	if len(data) < hsize || !bytes.HasPrefix(data, merkleMagic) {
+		return nil, ErrInvalidFooter
+	}
+
+	flags := data[len(merkleMagic)]
+	f := &MerkleFooter{
+		Count: binary.BigEndian.Uint64(data[len(merkleMagic)+1:]),
+	}
+	copy(f.Root[:], data[len(merkleMagic)+1+8:])
+
+	leaves := data[hsize:]
+	if flags&merkleWithTree == 0 {
+		if len(leaves) != 0 {
+			return nil, ErrInvalidFooter
+		}
+		return f, nil
+	}
+
+	if len(leaves)%sha256.Size != 0 || uint64(len(leaves)/sha256.Size) != f.Count { // Count*sha256.Size may overflow.
+		return nil, ErrInvalidFooter
+	}
+	f.Leaves = make([][sha256.Size]byte, f.Count)
+	for i := range f.Leaves {
+		copy(f.Leaves[i][:], leaves[i*sha256.Size:])
+	}
+	if NewMerkleTree(f.Leaves).Root() != f.Root {
+		return nil, ErrRootMismatch
+	}
+
+	return f, nil
+}
+
+// Tree returns the tree stored in the footer, or nil when written without it.
+func (f *MerkleFooter) Tree() *MerkleTree {
+	if f.Leaves == nil {
+		return nil
+	}
+	return NewMerkleTree(f.Leaves)
+}
+
+func (f *MerkleFooter) marshal() []byte {
+	data := append([]byte(nil), merkleMagic...)
+	if f.Leaves != nil {
+		data = append(data, merkleWithTree)
+	} else {
+		data = append(data, 0)
+	}
+
+	var count [8]byte
+	binary.BigEndian.PutUint64(count[:], f.Count)
+	data = append(data, count[:]...)
+	data = append(data, f.Root[:]...)
+
+	for _, leaf := range f.Leaves {
+		data = append(data, leaf[:]...)
+	}
+	return data
+}
+
+////////////////////////////
+//                        //
+// MerkleReader           //
+//                        //
+////////////////////////////
+
+// A MerkleReader reads the blocks written by a MerkleWriter and verifies them against the footer.
+type MerkleReader struct {
+	tr     *trailerReader
+	tree   MerkleTree
+	footer *MerkleFooter
+}
+
+// NewMerkleReader returns a new MerkleReader reading blocks of size up to size from r.
+// Provided r must be a block reader.
+// Blocks are yielded before being verified; once all the blocks are read,
+// io.EOF is returned only if they match the footer, ErrRootMismatch otherwise.
+func NewMerkleReader(r io.Reader, size int) *MerkleReader {
+	mr := &MerkleReader{}
+	mr.tr = newTrailerReader(r, size, mr.tree.Add, func(data []byte) (bool, error) {
+		if data == nil {
+			return true, ErrInvalidFooter
+		}
+
+		footer, err := ParseMerkleFooter(data)
+		if err != nil {
+			return true, err
+		}
+		if footer.Count != mr.tree.Len() || footer.Root != mr.tree.Root() {
+			return true, ErrRootMismatch
+		}
+
+		mr.footer = footer
+		return true, nil
+	})
+
+	return mr
+}
+
+func (r *MerkleReader) Read(p []byte) (n int, err error) {
+	return r.tr.Read(p)
+}
+
+// Footer returns the verified footer once all the blocks have been read, nil otherwise.
+func (r *MerkleReader) Footer() *MerkleFooter {
+	return r.footer
+}
+
+// Tree returns the tree of the blocks read so far.
+func (r *MerkleReader) Tree() *MerkleTree {
+	return &r.tree
+}
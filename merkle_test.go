@@ -0,0 +1,159 @@
+package blockio_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerkleTree_Proof(t *testing.T) {
+	for count := 1; count <= 17; count++ {
+		tree := blockio.NewMerkleTree(nil)
+		for i := 0; i < count; i++ {
+			tree.Add([]byte(fmt.Sprint(i)))
+		}
+		root := tree.Root()
+
+		for i := 0; i < count; i++ {
+			proof, err := tree.Proof(uint64(i))
+			assert.NoError(t, err)
+
+			assert.True(t, blockio.VerifyProof([]byte(fmt.Sprint(i)), uint64(i), uint64(count), proof, root), "count=%d i=%d", count, i)
+			assert.False(t, blockio.VerifyProof([]byte("x"), uint64(i), uint64(count), proof, root))
+			if count > 1 {
+				assert.False(t, blockio.VerifyProof([]byte(fmt.Sprint(i)), uint64((i+1)%count), uint64(count), proof, root))
+			}
+		}
+
+		_, err := tree.Proof(uint64(count))
+		assert.ErrorIs(t, err, blockio.ErrIndexOutOfRange)
+	}
+
+	//
+	// RFC 6962 root of two leaves
+
+	tree := blockio.NewMerkleTree(nil)
+	tree.Add([]byte("a"))
+	tree.Add([]byte("b"))
+	a, b := blockio.MerkleLeaf([]byte("a")), blockio.MerkleLeaf([]byte("b"))
+	assert.Equal(t, sha256.Sum256(append(append([]byte{1}, a[:]...), b[:]...)), tree.Root())
+}
+
+func TestMerkleWriter_Write(t *testing.T) {
+	for _, withTree := range []bool{false, true} {
+		var buf bytes.Buffer
+		w := blockio.NewMerkleWriter(blockio.NewWriter16(&buf), withTree)
+
+		for _, block := range []string{"data", "datum", "last"} {
+			n, err := w.Write([]byte(block))
+			assert.NoError(t, err)
+			assert.Equal(t, len(block), n)
+		}
+		assert.NoError(t, w.Close())
+		_, err := w.Write([]byte("data"))
+		assert.ErrorIs(t, err, blockio.ErrClosed)
+
+		//
+		// Read and verify
+
+		r := blockio.NewMerkleReader(blockio.NewReader16(bytes.NewReader(buf.Bytes())), blockio.MaxBlock16)
+		blocks, err := readAll(r, 64)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"data", "datum", "last"}, blocks)
+
+		footer := r.Footer()
+		if assert.NotNil(t, footer) {
+			assert.Equal(t, uint64(3), footer.Count)
+			assert.Equal(t, w.Tree().Root(), footer.Root)
+			assert.Equal(t, withTree, footer.Tree() != nil)
+		}
+
+		//
+		// Proof from the footer's tree
+
+		if withTree {
+			proof, err := footer.Tree().Proof(1)
+			assert.NoError(t, err)
+			assert.True(t, blockio.VerifyProof([]byte("datum"), 1, footer.Count, proof, footer.Root))
+		}
+	}
+}
+
+func TestMerkleReader_Read(t *testing.T) {
+	var buf bytes.Buffer
+	w := blockio.NewMerkleWriter(blockio.NewWriter16(&buf), false)
+	for _, block := range []string{"data", "datum", "last"} {
+		_, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, w.Close())
+	blocks := rawBlocks(t, buf.Bytes())
+
+	//
+	// Tampered block
+
+	r := blockio.NewMerkleReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[0], []byte("dotum"), blocks[2], blocks[3]))), blockio.MaxBlock16)
+	_, err := readAll(r, 64)
+	assert.ErrorIs(t, err, blockio.ErrRootMismatch)
+	assert.Nil(t, r.Footer())
+
+	//
+	// Missing footer
+
+	r = blockio.NewMerkleReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[:3]...))), blockio.MaxBlock16)
+	read, err := readAll(r, 64)
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter)
+	assert.Equal(t, []string{"data", "datum"}, read)
+
+	r = blockio.NewMerkleReader(blockio.NewReader16(bytes.NewReader(nil)), blockio.MaxBlock16)
+	_, err = r.Read(make([]byte, 64))
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter)
+	_, err = r.Read(make([]byte, 64))
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter) // Sticky.
+}
+
+func TestMerkle_Decoder(t *testing.T) {
+	var buf bytes.Buffer
+	w := blockio.NewMerkleWriter(blockio.NewWriter16(&buf), true)
+
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write("record"))
+	assert.NoError(t, w.Close())
+
+	decoder := blockio.NewBlockDecoder(blockio.NewMerkleReader(blockio.NewReader16(&buf), blockio.MaxBlock16), json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "record", v)
+	assert.ErrorIs(t, decoder.Read(&v), io.EOF)
+}
+
+func TestParseMerkleFooter(t *testing.T) {
+	footer := func(flags byte, count uint64, leaves int) []byte {
+		data := append([]byte("BIOM"), flags, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(data[5:], count)
+		data = append(data, make([]byte, sha256.Size)...) // Root.
+		return append(data, make([]byte, leaves)...)
+	}
+
+	//
+	// Count overflowing the size of the leaves
+
+	_, err := blockio.ParseMerkleFooter(footer(0x01, 1<<59, 0))
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter)
+
+	_, err = blockio.ParseMerkleFooter(footer(0x01, 1<<59+1, sha256.Size))
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter)
+
+	//
+	// Partial leaf
+
+	_, err = blockio.ParseMerkleFooter(footer(0x01, 1, sha256.Size+1))
+	assert.ErrorIs(t, err, blockio.ErrInvalidFooter)
+}
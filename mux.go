@@ -0,0 +1,475 @@
+package blockio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Types of the mux frames: [size][type][channel ID][payload].
+const (
+	muxOpen byte = iota
+	muxData
+	muxClose
+	muxWindow // Payload: credits granted in bytes (uint32).
+)
+
+const (
+	muxHeader     = 1 + 4
+	muxMaxPayload = MaxBlock16 - muxHeader
+)
+
+var (
+	// ErrMuxClosed is returned when using a closed Mux or one of its channels after the Mux is closed.
+	ErrMuxClosed = errors.New("mux closed")
+	// ErrChannelClosed is returned when writing to a channel closed by either side.
+	ErrChannelClosed = errors.New("channel closed")
+	// ErrFlowControl is returned when the peer sends more data than the credits it has been granted.
+	ErrFlowControl = errors.New("flow control violation")
+)
+
+// A MuxConfig configures a Mux.
+type MuxConfig struct {
+	// Client selects odd IDs for the channels opened locally, even IDs otherwise.
+	// Both sides of a connection must use a different value.
+	Client bool
+	// Window is the number of bytes a side may send on a channel before being granted more credits
+	// by the peer, that is the maximum number of bytes buffered per channel (default 256 KiB).
+	// Credits are granted as the data is read. Both sides must use the same value.
+	Window int
+	// Backlog is the number of channels opened by the peer waiting to be accepted (default 16).
+	// Once reached, reading frames from the connection is suspended until a channel is accepted.
+	Backlog int
+}
+
+// A Mux multiplexes logical channels over a single block stream.
+type Mux struct {
+	conn io.ReadWriteCloser
+	cfg  MuxConfig
+
+	wmu sync.Mutex
+	w   io.Writer
+	buf []byte
+
+	mu       sync.Mutex
+	channels map[uint32]*Channel
+	nextID   uint32
+	err      error // Sticky error once the mux is closed.
+	accept   chan *Channel
+	done     chan struct{}
+}
+
+// NewMux returns a new Mux over conn and starts reading frames from it.
+func NewMux(conn io.ReadWriteCloser, cfg MuxConfig) *Mux {
+	if cfg.Window <= 0 {
+		cfg.Window = 256 << 10
+	}
+	if cfg.Backlog <= 0 {
+		cfg.Backlog = 16
+	}
+
+	m := &Mux{
+		conn:     conn,
+		cfg:      cfg,
+		w:        NewWriter16(conn),
+		channels: map[uint32]*Channel{},
+		nextID:   2,
+		accept:   make(chan *Channel, cfg.Backlog),
+		done:     make(chan struct{}),
+	}
+	if cfg.Client {
+		m.nextID = 1
+	}
+
+	go m.loop()
+	return m
+}
+
+// Open opens a new channel with the peer.
+func (m *Mux) Open() (*Channel, error) {
+	m.mu.Lock()
+	if m.err != nil {
+		err := m.err
+		m.mu.Unlock()
+		return nil, err
+	}
+
+	id := m.nextID
+	m.nextID += 2
+	c := newChannel(m, id)
+	m.channels[id] = c
+	m.mu.Unlock()
+
+	if err := m.writeFrame(muxOpen, id, nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Accept waits for and returns the next channel opened by the peer.
+func (m *Mux) Accept() (*Channel, error) {
+	select {
+	case c := <-m.accept:
+		return c, nil
+	case <-m.done:
+		return nil, m.Err()
+	}
+}
+
+// Close closes the underlying connection and all the channels.
+func (m *Mux) Close() error {
+	err := m.conn.Close()
+	m.shutdown(ErrMuxClosed)
+	return err
+}
+
+// Err returns the error that stopped the mux, if any.
+func (m *Mux) Err() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.err
+}
+
+// Done returns a channel closed once the mux is stopped.
+func (m *Mux) Done() <-chan struct{} {
+	return m.done
+}
+
+func (m *Mux) writeFrame(typ byte, id uint32, payload []byte) error {
+	m.wmu.Lock()
+	defer m.wmu.Unlock()
+
+	if err := m.Err(); err != nil {
+		return err
+	}
+
+	m.buf = append(m.buf[:0], typ, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(m.buf[1:], id)
+	m.buf = append(m.buf, payload...)
+
+	if _, err := m.w.Write(m.buf); err != nil {
+		m.shutdown(err)
+		return err
+	}
+	return nil
+}
+
+// loop reads the frames and dispatches them to the channels.
+func (m *Mux) loop() {
+	r := NewReader16(m.conn)
+	buf := make([]byte, MaxBlock16)
+
+	for {
+		n, err := r.Read(buf)
+		if err != nil {
+			m.shutdown(err)
+			return
+		}
+		if n < muxHeader {
+			m.shutdown(ErrInvalidFrame)
+			return
+		}
+
+		typ := buf[0]
+		id := binary.BigEndian.Uint32(buf[1:])
+		payload := buf[muxHeader:n]
+
+		m.mu.Lock()
+		c := m.channels[id]
+		m.mu.Unlock()
+
+		switch typ {
+		case muxOpen:
+			if c != nil || (id%2 == 1) == m.cfg.Client {
+				m.shutdown(ErrInvalidFrame) // Duplicate or wrong side ID.
+				return
+			}
+
+			c = newChannel(m, id)
+			m.mu.Lock()
+			m.channels[id] = c
+			m.mu.Unlock()
+
+			select {
+			case m.accept <- c:
+			case <-m.done:
+				return
+			}
+		case muxData:
+			if c != nil && !c.push(payload) {
+				m.shutdown(ErrFlowControl)
+				return
+			}
+		case muxWindow:
+			if len(payload) != 4 {
+				m.shutdown(ErrInvalidFrame)
+				return
+			}
+			if c != nil {
+				c.grant(int(binary.BigEndian.Uint32(payload)))
+			}
+		case muxClose:
+			if c != nil {
+				c.closeRemote()
+			}
+		default:
+			m.shutdown(ErrInvalidFrame)
+			return
+		}
+	}
+}
+
+// shutdown stops the mux with err, waking up all the channels.
+func (m *Mux) shutdown(err error) {
+	m.mu.Lock()
+	if m.err != nil {
+		m.mu.Unlock()
+		return
+	}
+	if errors.Is(err, io.EOF) {
+		err = ErrMuxClosed
+	}
+	m.err = err
+	channels := m.channels
+	m.channels = map[uint32]*Channel{}
+	close(m.done)
+	m.mu.Unlock()
+
+	m.conn.Close()
+	for _, c := range channels {
+		c.fail(err)
+	}
+}
+
+func (m *Mux) remove(id uint32) {
+	m.mu.Lock()
+	delete(m.channels, id)
+	m.mu.Unlock()
+}
+
+////////////////////////////
+//                        //
+// Channel                //
+//                        //
+////////////////////////////
+
+// A Channel is a logical bidirectional stream of a Mux.
+// Blocks can be exchanged over it with the block readers and writers, or Encoder and Decoder.
+type Channel struct {
+	m  *Mux
+	id uint32
+
+	mu       sync.Mutex
+	cond     *sync.Cond
+	buf      bytes.Buffer
+	received int // Bytes received and not granted back yet (buffered or read).
+	consumed int // Bytes read and not granted back yet.
+	credits  int // Bytes that can be sent.
+	deadline time.Time
+	timer    *time.Timer
+	local    bool  // Closed locally.
+	remote   bool  // Closed by the peer.
+	err      error // Mux error.
+}
+
+func newChannel(m *Mux, id uint32) *Channel {
+	c := &Channel{
+		m:       m,
+		id:      id,
+		credits: m.cfg.Window,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// ID returns the identifier of the channel.
+func (c *Channel) ID() uint32 {
+	return c.id
+}
+
+// Read reads data sent by the peer on the channel.
+// It returns io.EOF once the peer closed the channel and all the data is read.
+func (c *Channel) Read(p []byte) (n int, err error) {
+	c.mu.Lock()
+	for c.buf.Len() == 0 {
+		switch {
+		case c.local:
+			c.mu.Unlock()
+			return 0, ErrChannelClosed
+		case c.remote:
+			c.mu.Unlock()
+			return 0, io.EOF
+		case c.err != nil:
+			err = c.err
+			c.mu.Unlock()
+			return 0, err
+		}
+		c.cond.Wait()
+	}
+
+	n, _ = c.buf.Read(p)
+
+	// Grant credits back once half of the window has been consumed.
+	var credits int
+	c.consumed += n
+	if c.consumed >= c.m.cfg.Window/2 {
+		credits = c.consumed
+		c.received -= c.consumed
+		c.consumed = 0
+	}
+	c.mu.Unlock()
+
+	if credits > 0 {
+		var payload [4]byte
+		binary.BigEndian.PutUint32(payload[:], uint32(credits))
+		c.m.writeFrame(muxWindow, c.id, payload[:]) // A failure stops the mux and is reported by the next calls.
+	}
+
+	return n, nil
+}
+
+// Write writes p on the channel, split in frames if needed.
+// It blocks while the peer has not granted enough credits, until the write deadline if any.
+func (c *Channel) Write(p []byte) (n int, err error) {
+	for len(p) > 0 {
+		size, err := c.reserve(len(p))
+		if err != nil {
+			return n, err
+		}
+
+		if err = c.m.writeFrame(muxData, c.id, p[:size]); err != nil {
+			return n, err
+		}
+		n += size
+		p = p[size:]
+	}
+
+	return n, nil
+}
+
+// reserve waits for credits and consumes up to size of them.
+func (c *Channel) reserve(size int) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for {
+		switch {
+		case c.local, c.remote:
+			return 0, ErrChannelClosed
+		case c.err != nil:
+			return 0, c.err
+		case !c.deadline.IsZero() && !time.Now().Before(c.deadline):
+			return 0, os.ErrDeadlineExceeded
+		}
+
+		if c.credits > 0 {
+			break
+		}
+		c.cond.Wait()
+	}
+
+	if size > c.credits {
+		size = c.credits
+	}
+	if size > muxMaxPayload {
+		size = muxMaxPayload
+	}
+
+	c.credits -= size
+	return size, nil
+}
+
+// SetWriteDeadline sets the deadline for writes waiting for credits.
+// Once exceeded, writes fail with os.ErrDeadlineExceeded. A zero value for t means writes will not time out.
+func (c *Channel) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.deadline = t
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if !t.IsZero() {
+		c.timer = time.AfterFunc(time.Until(t), func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			c.cond.Broadcast()
+		})
+	}
+	c.cond.Broadcast()
+	return nil
+}
+
+// Close closes the channel on both sides. Buffered data not read yet is discarded.
+func (c *Channel) Close() error {
+	c.mu.Lock()
+	if c.local {
+		c.mu.Unlock()
+		return nil
+	}
+	c.local = true
+	c.buf.Reset()
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	remote := c.remote
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	c.m.remove(c.id)
+	if remote {
+		return nil // Already closed by the peer.
+	}
+	return c.m.writeFrame(muxClose, c.id, nil)
+}
+
+// push buffers data received from the peer.
+// It reports false when the peer exceeded its credits.
+func (c *Channel) push(data []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.received += len(data)
+	if c.received > c.m.cfg.Window {
+		return false
+	}
+	if c.local {
+		return true // Discarded.
+	}
+
+	c.buf.Write(data)
+	c.cond.Broadcast()
+	return true
+}
+
+// grant adds credits granted by the peer.
+func (c *Channel) grant(credits int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.credits += credits
+	c.cond.Broadcast()
+}
+
+func (c *Channel) closeRemote() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remote = true
+	c.cond.Broadcast()
+}
+
+func (c *Channel) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.err = err
+	c.cond.Broadcast()
+}
@@ -0,0 +1,197 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func newMuxPair(cfg blockio.MuxConfig) (*blockio.Mux, *blockio.Mux) {
+	c1, c2 := net.Pipe()
+
+	client := cfg
+	client.Client = true
+	return blockio.NewMux(c1, client), blockio.NewMux(c2, cfg)
+}
+
+func TestMux_Channel(t *testing.T) {
+	client, server := newMuxPair(blockio.MuxConfig{})
+	defer client.Close()
+	defer server.Close()
+
+	c1, err := client.Open()
+	assert.NoError(t, err)
+	c2, err := client.Open()
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(1), c1.ID())
+	assert.Equal(t, uint32(3), c2.ID())
+
+	s1, err := server.Accept()
+	assert.NoError(t, err)
+	s2, err := server.Accept()
+	assert.NoError(t, err)
+	assert.Equal(t, c1.ID(), s1.ID())
+	assert.Equal(t, c2.ID(), s2.ID())
+
+	//
+	// Independent streams
+
+	_, err = c2.Write([]byte("two"))
+	assert.NoError(t, err)
+	_, err = c1.Write([]byte("one"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 8)
+	n, err := s1.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "one", string(buf[:n]))
+	n, err = s2.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "two", string(buf[:n]))
+
+	//
+	// Large writes are split in frames
+
+	large := bytes.Repeat([]byte("large"), 30000)
+	go func() {
+		_, err := s1.Write(large)
+		assert.NoError(t, err)
+	}()
+	received := make([]byte, len(large))
+	_, err = io.ReadFull(c1, received)
+	assert.NoError(t, err)
+	assert.Equal(t, large, received)
+
+	//
+	// Close
+
+	assert.NoError(t, c2.Close())
+	_, err = s2.Read(buf)
+	assert.ErrorIs(t, err, io.EOF)
+	_, err = s2.Write([]byte("two"))
+	assert.ErrorIs(t, err, blockio.ErrChannelClosed)
+	_, err = c2.Write([]byte("two"))
+	assert.ErrorIs(t, err, blockio.ErrChannelClosed)
+	assert.NoError(t, s2.Close())
+
+	//
+	// Mux closed
+
+	assert.NoError(t, client.Close())
+	_, err = c1.Read(buf)
+	assert.ErrorIs(t, err, blockio.ErrMuxClosed)
+
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatal("server mux not stopped")
+	}
+	_, err = s1.Read(buf)
+	assert.Error(t, err)
+	_, err = server.Accept()
+	assert.Error(t, err)
+}
+
+func TestMux_Encoder(t *testing.T) {
+	client, server := newMuxPair(blockio.MuxConfig{})
+	defer client.Close()
+	defer server.Close()
+
+	c, err := client.Open()
+	assert.NoError(t, err)
+	s, err := server.Accept()
+	assert.NoError(t, err)
+
+	go func() {
+		encoder := blockio.NewBlock16Encoder(c, json.Marshal)
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, encoder.Write(i))
+		}
+		assert.NoError(t, c.Close())
+	}()
+
+	decoder := blockio.NewBlock16Decoder(s, json.Unmarshal)
+	for i := 0; i < 10; i++ {
+		var v int
+		assert.NoError(t, decoder.Read(&v))
+		assert.Equal(t, i, v)
+	}
+	assert.ErrorIs(t, decoder.Read(new(int)), io.EOF)
+}
+
+func TestMux_FlowControl(t *testing.T) {
+	client, server := newMuxPair(blockio.MuxConfig{Window: 16})
+	defer client.Close()
+	defer server.Close()
+
+	slow, err := client.Open()
+	assert.NoError(t, err)
+	fast, err := client.Open()
+	assert.NoError(t, err)
+	sslow, err := server.Accept()
+	assert.NoError(t, err)
+	sfast, err := server.Accept()
+	assert.NoError(t, err)
+
+	//
+	// Writer blocks once its credits are exhausted
+
+	assert.NoError(t, slow.SetWriteDeadline(time.Now().Add(50*time.Millisecond)))
+	n, err := slow.Write(make([]byte, 20))
+	assert.ErrorIs(t, err, os.ErrDeadlineExceeded)
+	assert.Equal(t, 16, n)
+
+	// The slow channel does not block the others.
+	_, err = fast.Write([]byte("fast"))
+	assert.NoError(t, err)
+
+	buf := make([]byte, 16)
+	n, err = sfast.Read(buf)
+	assert.NoError(t, err)
+	assert.Equal(t, "fast", string(buf[:n]))
+
+	//
+	// Reading grants credits back
+
+	assert.NoError(t, slow.SetWriteDeadline(time.Time{}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+
+		n, err := slow.Write(bytes.Repeat([]byte{'a'}, 32))
+		assert.NoError(t, err)
+		assert.Equal(t, 32, n)
+	}()
+
+	received := make([]byte, 48)
+	_, err = io.ReadFull(sslow, received)
+	assert.NoError(t, err)
+	assert.Equal(t, bytes.Repeat([]byte{'a'}, 32), received[16:])
+	<-done
+}
+
+func TestMux_FlowControlViolation(t *testing.T) {
+	c1, c2 := net.Pipe()
+	server := blockio.NewMux(c2, blockio.MuxConfig{Window: 4})
+
+	// A misbehaving peer ignoring its credits.
+	w := blockio.NewWriter16(c1)
+	_, err := w.Write([]byte{0, 0, 0, 0, 1})
+	assert.NoError(t, err)
+	_, err = w.Write([]byte{1, 0, 0, 0, 1, 'd', 'a', 't', 'a', '!'})
+	assert.NoError(t, err)
+
+	select {
+	case <-server.Done():
+	case <-time.After(time.Second):
+		t.Fatal("server mux not stopped")
+	}
+	assert.ErrorIs(t, server.Err(), blockio.ErrFlowControl)
+}
@@ -0,0 +1,269 @@
+package blockio
+
+import (
+	"errors"
+	"io"
+	"runtime"
+	"sync"
+)
+
+////////////////////////////
+//                        //
+// ParallelDecoder        //
+//                        //
+////////////////////////////
+
+type (
+	// A ParallelDecoder reads blocks sequentially and decodes them on a pool of workers.
+	// Decoded values are delivered in the original order of the blocks.
+	ParallelDecoder struct {
+		r        io.Reader
+		decode   Decode
+		newValue func() any
+		size     int
+		workers  int
+	}
+
+	decodeJob struct {
+		data []byte
+		v    any
+		err  error
+		done chan struct{}
+	}
+)
+
+// NewParallelDecoder decodes values from r using the given h on workers goroutines.
+// Provided r must be a block reader able to read blocks up to size.
+// newValue allocates the value passed to h for each block (e.g. func() any { return new(payload) }).
+// A workers value less or equal to zero uses runtime.GOMAXPROCS.
+func NewParallelDecoder(r io.Reader, size int, h Decode, newValue func() any, workers int) *ParallelDecoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	return &ParallelDecoder{
+		r:        r,
+		decode:   h,
+		newValue: newValue,
+		size:     size,
+		workers:  workers,
+	}
+}
+
+// ReadAll reads and decodes all the blocks until io.EOF, calling fn for each value in the blocks order.
+// At most twice the number of workers blocks are in memory at the same time.
+// It stops at the first error returned by the block reader, a decoding or fn.
+func (d *ParallelDecoder) ReadAll(fn func(v any) error) error {
+	window := 2 * d.workers
+	jobs := make(chan *decodeJob, window)
+	ordered := make(chan *decodeJob, window)
+	stop := make(chan struct{})
+
+	var wg sync.WaitGroup
+	wg.Add(d.workers)
+	for i := 0; i < d.workers; i++ {
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				job.v = d.newValue()
+				job.err = d.decode(job.data, job.v)
+				close(job.done)
+			}
+		}()
+	}
+
+	var rerr error
+	go func() {
+		defer close(ordered)
+		defer close(jobs)
+
+		buf := make([]byte, d.size)
+		for {
+			n, err := d.r.Read(buf[:cap(buf)])
+			if err != nil {
+				if !errors.Is(err, io.EOF) {
+					rerr = err
+				}
+				return
+			}
+
+			job := &decodeJob{
+				data: append([]byte(nil), buf[:n]...),
+				done: make(chan struct{}),
+			}
+
+			select {
+			case ordered <- job:
+			case <-stop:
+				return
+			}
+			jobs <- job // Never blocks longer than a decoding because ordered and jobs have the same capacity.
+		}
+	}()
+
+	var err error
+	for job := range ordered {
+		<-job.done
+		if err != nil {
+			continue // Drain after cancellation.
+		}
+
+		err = job.err
+		if err == nil {
+			err = fn(job.v)
+		}
+		if err != nil {
+			close(stop)
+		}
+	}
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	return rerr
+}
+
+////////////////////////////
+//                        //
+// ParallelEncoder        //
+//                        //
+////////////////////////////
+
+type (
+	// A ParallelEncoder encodes objects on a pool of workers and writes them as blocks
+	// in the order they have been submitted.
+	// It is safe for concurrent use, the blocks of concurrent writes are written in an unspecified order.
+	ParallelEncoder struct {
+		w       io.Writer
+		encode  Encode
+		jobs    chan *encodeJob
+		ordered chan *encodeJob
+		wg      sync.WaitGroup // Workers and writer goroutines.
+		pending sync.WaitGroup // Submitted but not written blocks.
+
+		smu    sync.RWMutex // Read-locked over the sends, locked by Close to close the channels.
+		closed bool
+
+		mu  sync.Mutex
+		err error
+	}
+
+	encodeJob struct {
+		v       any
+		payload []byte
+		err     error
+		done    chan struct{}
+	}
+)
+
+// ErrEncoderClosed is returned when writing to a closed ParallelEncoder.
+var ErrEncoderClosed = errors.New("encoder closed")
+
+// NewParallelEncoder encodes values to w using the given h on workers goroutines.
+// Provided w must be a block writer.
+// At most twice the number of workers values are in flight at the same time.
+// A workers value less or equal to zero uses runtime.GOMAXPROCS.
+func NewParallelEncoder(w io.Writer, h Encode, workers int) *ParallelEncoder {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	e := &ParallelEncoder{
+		w:       w,
+		encode:  h,
+		jobs:    make(chan *encodeJob, 2*workers),
+		ordered: make(chan *encodeJob, 2*workers),
+	}
+
+	e.wg.Add(workers + 1)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer e.wg.Done()
+			for job := range e.jobs {
+				job.payload, job.err = e.encode(job.v)
+				close(job.done)
+			}
+		}()
+	}
+
+	go func() {
+		defer e.wg.Done()
+		for job := range e.ordered {
+			<-job.done
+
+			if e.Err() == nil {
+				err := job.err
+				if err == nil {
+					_, err = e.w.Write(job.payload)
+				}
+				if err != nil {
+					e.mu.Lock()
+					e.err = err
+					e.mu.Unlock()
+				}
+			}
+			e.pending.Done()
+		}
+	}()
+
+	return e
+}
+
+// Write submits v to be encoded and written.
+// It blocks while the in-flight window is full.
+// The returned error is the first error that occurred on a previously submitted value, if any.
+func (e *ParallelEncoder) Write(v any) error {
+	e.smu.RLock()
+	defer e.smu.RUnlock()
+
+	if e.closed {
+		return ErrEncoderClosed
+	}
+	if err := e.Err(); err != nil {
+		return err
+	}
+
+	job := &encodeJob{
+		v:    v,
+		done: make(chan struct{}),
+	}
+
+	e.pending.Add(1)
+	e.ordered <- job
+	e.jobs <- job
+	return nil
+}
+
+// Flush waits until all the submitted values are written and returns the first error, if any.
+func (e *ParallelEncoder) Flush() error {
+	e.pending.Wait()
+	return e.Err()
+}
+
+// Close flushes the submitted values, stops the workers and returns the first error, if any.
+// It does not close the underlying writer.
+// Pending writes are completed before closing.
+func (e *ParallelEncoder) Close() error {
+	e.smu.Lock()
+	if e.closed {
+		e.smu.Unlock()
+		return e.Err()
+	}
+	e.closed = true
+	close(e.ordered)
+	close(e.jobs)
+	e.smu.Unlock()
+
+	e.wg.Wait()
+
+	return e.Err()
+}
+
+// Err returns the first error that occurred, if any.
+func (e *ParallelEncoder) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.err
+}
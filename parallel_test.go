@@ -0,0 +1,137 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelDecoder_ReadAll(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := blockio.NewBlock16Encoder(&buf, json.Marshal)
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, encoder.Write(i))
+	}
+	data := buf.Bytes()
+
+	//
+	// Ordered values
+
+	decoder := blockio.NewParallelDecoder(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, json.Unmarshal, func() any { return new(int) }, 4)
+
+	var values []int
+	err := decoder.ReadAll(func(v any) error {
+		values = append(values, *v.(*int))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Len(t, values, 100)
+	for i, v := range values {
+		assert.Equal(t, i, v)
+	}
+
+	//
+	// First error cancellation
+
+	stop := errors.New("stop")
+	decoder = blockio.NewParallelDecoder(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, json.Unmarshal, func() any { return new(int) }, 4)
+
+	values = values[:0]
+	err = decoder.ReadAll(func(v any) error {
+		if *v.(*int) == 42 {
+			return stop
+		}
+		values = append(values, *v.(*int))
+		return nil
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Len(t, values, 42)
+
+	//
+	// Decoding error
+
+	decoder = blockio.NewParallelDecoder(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, json.Unmarshal, func() any { return new(string) }, 0)
+	err = decoder.ReadAll(func(v any) error { return nil })
+	var jerr *json.UnmarshalTypeError
+	assert.ErrorAs(t, err, &jerr)
+
+	//
+	// Reader error
+
+	decoder = blockio.NewParallelDecoder(blockio.NewReader16(io.MultiReader(bytes.NewReader(data[:3]), failingReader{})), blockio.MaxBlock16, json.Unmarshal, func() any { return new(int) }, 2)
+	err = decoder.ReadAll(func(v any) error { return nil })
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, io.ErrClosedPipe
+}
+
+func TestParallelEncoder_Write(t *testing.T) {
+	var buf bytes.Buffer
+	encoder := blockio.NewParallelEncoder(blockio.NewWriter16(&buf), json.Marshal, 4)
+
+	for i := 0; i < 100; i++ {
+		assert.NoError(t, encoder.Write(i))
+	}
+	assert.NoError(t, encoder.Flush())
+
+	decoder := blockio.NewBlock16Decoder(bytes.NewReader(buf.Bytes()), json.Unmarshal)
+	for i := 0; i < 100; i++ {
+		var v int
+		assert.NoError(t, decoder.Read(&v))
+		assert.Equal(t, i, v)
+	}
+	assert.ErrorIs(t, decoder.Read(new(int)), io.EOF)
+
+	assert.NoError(t, encoder.Close())
+	assert.ErrorIs(t, encoder.Write(0), blockio.ErrEncoderClosed)
+
+	//
+	// First error
+
+	buf.Reset()
+	encoder = blockio.NewParallelEncoder(blockio.NewWriter8(&buf), json.Marshal, 2)
+	assert.NoError(t, encoder.Write("data"))
+	assert.NoError(t, encoder.Write(string(bytes.Repeat([]byte{'a'}, blockio.MaxBlock8))))
+	assert.ErrorIs(t, encoder.Flush(), blockio.ErrBlockSize)
+	assert.ErrorIs(t, encoder.Write("data"), blockio.ErrBlockSize)
+	assert.ErrorIs(t, encoder.Close(), blockio.ErrBlockSize)
+	assert.Equal(t, "\x06\"data\"", buf.String())
+}
+
+func TestParallelEncoder_WriteClose(t *testing.T) {
+	slow := func(v any) ([]byte, error) {
+		time.Sleep(time.Millisecond)
+		return json.Marshal(v)
+	}
+	encoder := blockio.NewParallelEncoder(blockio.NewWriter16(io.Discard), slow, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if err := encoder.Write(j); err != nil {
+					assert.ErrorIs(t, err, blockio.ErrEncoderClosed)
+					return
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond) // Let the writers block on the full window.
+	assert.NoError(t, encoder.Close())
+	wg.Wait()
+	assert.ErrorIs(t, encoder.Write(0), blockio.ErrEncoderClosed)
+}
@@ -25,6 +25,7 @@ type reader struct {
 	src   io.Reader
 	size  int
 	rsize func() (int, error)
+	read  int // Bytes read from src by the last Read.
 }
 
 // NewReader8 returns a new reader that is able to read blocks of size MaxBlock8.
@@ -36,7 +37,7 @@ func NewReader8(r io.Reader) io.Reader {
 	buf := make([]byte, 1)
 
 	r8.rsize = func() (int, error) {
-		_, err := r8.src.Read(buf)
+		_, err := r8.readFull(buf)
 		if err != nil {
 			return 0, err
 		}
@@ -56,7 +57,7 @@ func NewReader16(r io.Reader) io.Reader {
 	buf := make([]byte, 2)
 
 	r16.rsize = func() (int, error) {
-		_, err := r16.src.Read(buf)
+		_, err := r16.readFull(buf)
 		if err != nil {
 			return 0, err
 		}
@@ -76,7 +77,7 @@ func NewReader24(r io.Reader) io.Reader {
 	buf := make([]byte, 4)
 
 	r24.rsize = func() (int, error) {
-		_, err := r24.src.Read(buf[1:]) // 3 bytes because we work on 24bit. We let to zero the fourth byte at index 0 for binary.BigEndian.Uint32's behavior.
+		_, err := r24.readFull(buf[1:]) // 3 bytes because we work on 24bit. We let to zero the fourth byte at index 0 for binary.BigEndian.Uint32's behavior.
 		if err != nil {
 			return 0, err
 		}
@@ -100,7 +101,7 @@ func NewReader24Custom(r io.Reader, size int) (io.Reader, error) {
 	buf := make([]byte, 4)
 
 	r24c.rsize = func() (int, error) {
-		_, err := r24c.src.Read(buf[1:]) // 3 bytes because we work on 24bit. We let to zero the fourth byte at index 0 for binary.BigEndian.Uint32's behavior.
+		_, err := r24c.readFull(buf[1:]) // 3 bytes because we work on 24bit. We let to zero the fourth byte at index 0 for binary.BigEndian.Uint32's behavior.
 		if err != nil {
 			return 0, err
 		}
@@ -120,7 +121,7 @@ func NewReader32(r io.Reader) io.Reader {
 	buf := make([]byte, 4)
 
 	r32.rsize = func() (int, error) {
-		_, err := r32.src.Read(buf)
+		_, err := r32.readFull(buf)
 		if err != nil {
 			return 0, err
 		}
@@ -144,7 +145,7 @@ func NewReader32Custom(r io.Reader, size int) (io.Reader, error) {
 	buf := make([]byte, 4)
 
 	r32c.rsize = func() (int, error) {
-		_, err := r32c.src.Read(buf)
+		_, err := r32c.readFull(buf)
 		if err != nil {
 			return 0, err
 		}
@@ -159,11 +160,30 @@ func (r *reader) Read(p []byte) (n int, err error) {
 	if cap(p) < r.size {
 		return 0, ErrBlockSizeTooSmall
 	}
+	r.read = 0
 
 	n, err = r.rsize()
 	if err != nil {
 		return 0, err
 	}
+	if n > r.size {
+		return 0, ErrSizeTooLarge
+	}
+
+	n, err = r.readFull(p[:n])
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF // The block header has already been read.
+	}
+	return n, err
+}
+
+func (r *reader) readFull(p []byte) (int, error) {
+	n, err := io.ReadFull(r.src, p)
+	r.read += n
+	return n, err
+}
 
-	return r.src.Read(p[:n])
+// consumed returns the number of bytes read from the source by the last Read.
+func (r *reader) consumed() int {
+	return r.read
 }
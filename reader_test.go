@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"testing"
+	"testing/iotest"
 
 	"github.com/mdouchement/blockio"
 	"github.com/stretchr/testify/assert"
@@ -203,6 +204,44 @@ func TestReader32Custom_Read(t *testing.T) {
 
 	block = block[:justEnoughSize]
 	n, err = r.Read(block)
-	assert.ErrorAs(t, err, &io.EOF)
+	assert.ErrorIs(t, err, io.EOF)
 	assert.Equal(t, 0, n)
 }
+
+func TestReader_ReadPartial(t *testing.T) {
+	// Sources returning less bytes than requested (e.g. network connections).
+	buf := bytes.NewBuffer([]byte{0, 4, 'd', 'a', 't', 'a', 0, 5, 'd', 'a', 't', 'u', 'm'})
+	r := blockio.NewReader16(iotest.OneByteReader(buf))
+
+	block := make([]byte, blockio.MaxBlock16)
+	n, err := r.Read(block)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("data"), block[:n])
+
+	n, err = r.Read(block)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("datum"), block[:n])
+
+	//
+	// Truncated block
+
+	r = blockio.NewReader16(bytes.NewBuffer([]byte{0, 5, 'd', 'a'}))
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	r = blockio.NewReader16(bytes.NewBuffer([]byte{0, 5}))
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	r = blockio.NewReader16(bytes.NewBuffer([]byte{0}))
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+
+	//
+	// Declared length larger than the reader size
+
+	r, err = blockio.NewReader24Custom(bytes.NewBuffer([]byte{0, 0, 5, 'd', 'a', 't', 'u', 'm'}), 4)
+	assert.NoError(t, err)
+	_, err = r.Read(make([]byte, 4))
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+}
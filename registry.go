@@ -0,0 +1,181 @@
+package blockio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sync"
+)
+
+var (
+	// ErrDuplicateRegistration is returned when a name or a type is registered twice.
+	ErrDuplicateRegistration = errors.New("duplicate registration")
+	// ErrUnregisteredType is returned when encoding a value whose type is not registered.
+	ErrUnregisteredType = errors.New("unregistered type")
+	// ErrUnknownTypeName is returned when decoding a block tagged with an unknown type name.
+	ErrUnknownTypeName = errors.New("unknown type name")
+)
+
+// A Registry maps Go types to stable names, in the manner of gob.Register.
+// It is safe for concurrent use.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		types: map[string]reflect.Type{},
+		names: map[reflect.Type]string{},
+	}
+}
+
+// Register records the concrete type of v under name.
+// Values registered as a pointer type are decoded as pointers, others as values.
+func (r *Registry) Register(name string, v any) error {
+	t := reflect.TypeOf(v)
+	if t == nil {
+		return fmt.Errorf("%w: nil", ErrUnregisteredType)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.types[name]; ok {
+		return fmt.Errorf("%w: name %q", ErrDuplicateRegistration, name)
+	}
+	if _, ok := r.names[t]; ok {
+		return fmt.Errorf("%w: type %s", ErrDuplicateRegistration, t)
+	}
+
+	r.types[name] = t
+	r.names[t] = name
+	return nil
+}
+
+func (r *Registry) name(v any) (string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	name, ok := r.names[reflect.TypeOf(v)]
+	if !ok {
+		return "", fmt.Errorf("%w: %T", ErrUnregisteredType, v)
+	}
+	return name, nil
+}
+
+func (r *Registry) typ(name string) (reflect.Type, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, ok := r.types[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownTypeName, name)
+	}
+	return t, nil
+}
+
+////////////////////////////
+//                        //
+// AnyEncoder             //
+//                        //
+////////////////////////////
+
+// An AnyEncoder encodes objects of registered types and writes them as blocks tagged with their type name.
+type AnyEncoder struct {
+	w        io.Writer
+	encode   Encode
+	registry *Registry
+	buf      []byte
+}
+
+// NewAnyEncoder encodes values to w using the given h and the type names of registry.
+// Provided w must be a block writer.
+func NewAnyEncoder(w io.Writer, h Encode, registry *Registry) *AnyEncoder {
+	return &AnyEncoder{
+		w:        w,
+		encode:   h,
+		registry: registry,
+	}
+}
+
+// Write writes the type name of v followed by its marshalized bytes.
+func (e *AnyEncoder) Write(v any) error {
+	name, err := e.registry.name(v)
+	if err != nil {
+		return err
+	}
+
+	payload, err := e.encode(v)
+	if err != nil {
+		return err
+	}
+
+	var l [binary.MaxVarintLen64]byte
+	e.buf = append(e.buf[:0], l[:binary.PutUvarint(l[:], uint64(len(name)))]...)
+	e.buf = append(e.buf, name...)
+	e.buf = append(e.buf, payload...)
+
+	_, err = e.w.Write(e.buf)
+	return err
+}
+
+////////////////////////////
+//                        //
+// AnyDecoder             //
+//                        //
+////////////////////////////
+
+// An AnyDecoder reads blocks written by an AnyEncoder and decodes them in values of their registered type.
+type AnyDecoder struct {
+	d        *Decoder
+	decode   Decode
+	registry *Registry
+}
+
+// NewAnyDecoder decodes values from r using the given h and the types of registry.
+// Provided r must be a block reader and buf must be large enough to handle blocks.
+func NewAnyDecoder(r io.Reader, h Decode, registry *Registry, buf []byte) *AnyDecoder {
+	return &AnyDecoder{
+		d:        NewBlockDecoder(r, h, buf),
+		decode:   h,
+		registry: registry,
+	}
+}
+
+// ReadAny reads the next block and returns a freshly allocated value of its registered type.
+func (d *AnyDecoder) ReadAny() (any, error) {
+	data, err := d.d.ReadBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	l, n := binary.Uvarint(data)
+	if n <= 0 || uint64(len(data)-n) < l {
+		return nil, ErrInvalidFrame
+	}
+	name := string(data[n : n+int(l)])
+
+	t, err := d.registry.typ(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Kind() == reflect.Ptr {
+		v := reflect.New(t.Elem())
+		if err = d.decode(data[n+int(l):], v.Interface()); err != nil {
+			return nil, err
+		}
+		return v.Interface(), nil
+	}
+
+	v := reflect.New(t)
+	if err = d.decode(data[n+int(l):], v.Interface()); err != nil {
+		return nil, err
+	}
+	return v.Elem().Interface(), nil
+}
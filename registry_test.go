@@ -0,0 +1,60 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+type (
+	circle struct {
+		Radius float64
+	}
+
+	square struct {
+		Side float64
+	}
+)
+
+func TestAnyEncoder_Write(t *testing.T) {
+	registry := blockio.NewRegistry()
+	assert.NoError(t, registry.Register("circle", circle{}))
+	assert.NoError(t, registry.Register("square", &square{}))
+	assert.ErrorIs(t, registry.Register("circle", 42), blockio.ErrDuplicateRegistration)
+	assert.ErrorIs(t, registry.Register("other", circle{}), blockio.ErrDuplicateRegistration)
+
+	var buf bytes.Buffer
+	encoder := blockio.NewAnyEncoder(blockio.NewWriter16(&buf), json.Marshal, registry)
+	assert.NoError(t, encoder.Write(circle{Radius: 1}))
+	assert.NoError(t, encoder.Write(&square{Side: 2}))
+	assert.ErrorIs(t, encoder.Write(square{Side: 2}), blockio.ErrUnregisteredType)
+	assert.Equal(t, "\x00\x13\x06circle{\"Radius\":1}", buf.String()[:21])
+
+	decoder := blockio.NewAnyDecoder(blockio.NewReader16(&buf), json.Unmarshal, registry, make([]byte, blockio.MaxBlock16))
+
+	v, err := decoder.ReadAny()
+	assert.NoError(t, err)
+	assert.Equal(t, circle{Radius: 1}, v)
+
+	v, err = decoder.ReadAny()
+	assert.NoError(t, err)
+	assert.Equal(t, &square{Side: 2}, v)
+
+	_, err = decoder.ReadAny()
+	assert.ErrorIs(t, err, io.EOF)
+
+	//
+	// Unknown name
+
+	buf.Reset()
+	encoder = blockio.NewAnyEncoder(blockio.NewWriter16(&buf), json.Marshal, registry)
+	assert.NoError(t, encoder.Write(circle{Radius: 1}))
+
+	decoder = blockio.NewAnyDecoder(blockio.NewReader16(&buf), json.Unmarshal, blockio.NewRegistry(), make([]byte, blockio.MaxBlock16))
+	_, err = decoder.ReadAny()
+	assert.ErrorIs(t, err, blockio.ErrUnknownTypeName)
+}
@@ -0,0 +1,432 @@
+// Package rpc provides request/response calls over a blockio.Conn.
+//
+// Each request and response is a message of the connection:
+//
+//	request:  [kindRequest][id uint64][method length uvarint][method][payload]
+//	response: [kindResponse][id uint64][status][payload]
+//
+// The payloads are the arguments and replies encoded with the configured Encode/Decode,
+// or the error message for failed calls.
+package rpc
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/mdouchement/blockio"
+)
+
+const (
+	kindRequest byte = iota + 1
+	kindResponse
+)
+
+// Status of a response.
+const (
+	statusOK byte = iota
+	statusError
+	statusUnknownMethod
+)
+
+const headerSize = 1 + 8
+
+var (
+	// ErrShutdown is returned by calls once the connection is closed.
+	ErrShutdown = errors.New("connection shut down")
+	// ErrUnknownMethod is returned when the server has no handler for the called method.
+	ErrUnknownMethod = errors.New("unknown method")
+	// ErrInvalidMessage is returned when a message received by a client or a server is malformed.
+	ErrInvalidMessage = errors.New("invalid rpc message")
+)
+
+// A ServerError is returned by Client.Call when the handler of the method failed.
+type ServerError struct {
+	Method  string
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Method, e.Message)
+}
+
+// A Config configures a Client or a Server.
+type Config struct {
+	// Encode encodes the arguments and replies (default json.Marshal).
+	Encode blockio.Encode
+	// Decode decodes the arguments and replies (default json.Unmarshal).
+	Decode blockio.Decode
+	// Timeout bounds the duration of a call, zero means no timeout.
+	// Client.Call applies it when its context has no deadline, the Server applies it to the context of the handlers.
+	Timeout time.Duration
+}
+
+func (cfg *Config) normalize() {
+	if cfg.Encode == nil {
+		cfg.Encode = json.Marshal
+	}
+	if cfg.Decode == nil {
+		cfg.Decode = json.Unmarshal
+	}
+}
+
+func appendHeader(buf []byte, kind byte, id uint64) []byte {
+	var b [headerSize]byte
+	b[0] = kind
+	binary.BigEndian.PutUint64(b[1:], id)
+	return append(buf, b[:]...)
+}
+
+// parseHeader returns the ID and the remaining data of a message of the given kind.
+func parseHeader(msg []byte, kind byte) (uint64, []byte, error) {
+	if len(msg) < headerSize || msg[0] != kind {
+		return 0, nil, ErrInvalidMessage
+	}
+
+	return binary.BigEndian.Uint64(msg[1:]), msg[headerSize:], nil
+}
+
+// isClosed reports whether err is due to a connection closed by either side.
+func isClosed(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe) || errors.Is(err, net.ErrClosed)
+}
+
+////////////////////////////
+//                        //
+// Client                 //
+//                        //
+////////////////////////////
+
+type (
+	// A Client calls methods of a Server over a connection.
+	// Calls can be performed concurrently and are multiplexed over the connection.
+	Client struct {
+		conn *blockio.Conn
+		cfg  Config
+
+		mu      sync.Mutex
+		nextID  uint64
+		pending map[uint64]chan response
+		err     error // Sticky error once the client is shut down.
+		done    chan struct{}
+	}
+
+	response struct {
+		status  byte
+		payload []byte
+	}
+)
+
+// NewClient returns a new Client over conn and starts reading the responses from it.
+func NewClient(conn *blockio.Conn, cfg Config) *Client {
+	cfg.normalize()
+
+	c := &Client{
+		conn:    conn,
+		cfg:     cfg,
+		pending: map[uint64]chan response{},
+		done:    make(chan struct{}),
+	}
+
+	go c.loop()
+	return c
+}
+
+// Call calls method with args and decodes the result in reply, if not nil.
+// It returns a *ServerError when the handler failed and ctx.Err() when ctx is done before the response is received.
+// Writing the request is bounded by the write deadline of the connection, not by ctx.
+func (c *Client) Call(ctx context.Context, method string, args, reply any) error {
+	if c.cfg.Timeout > 0 {
+		if _, ok := ctx.Deadline(); !ok {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, c.cfg.Timeout)
+			defer cancel()
+		}
+	}
+
+	payload, err := c.cfg.Encode(args)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	if c.err != nil {
+		err = c.err
+		c.mu.Unlock()
+		return err
+	}
+	c.nextID++
+	id := c.nextID
+	ch := make(chan response, 1)
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	msg := appendHeader(make([]byte, 0, headerSize+binary.MaxVarintLen64+len(method)+len(payload)), kindRequest, id)
+	var l [binary.MaxVarintLen64]byte
+	msg = append(msg, l[:binary.PutUvarint(l[:], uint64(len(method)))]...)
+	msg = append(msg, method...)
+	msg = append(msg, payload...)
+
+	if err = c.conn.WriteMessage(msg); err != nil {
+		c.remove(id)
+		return err
+	}
+
+	var resp response
+	select {
+	case resp = <-ch:
+	case <-ctx.Done():
+		c.remove(id) // A late response is discarded.
+		return ctx.Err()
+	case <-c.done:
+		return c.Err()
+	}
+
+	switch resp.status {
+	case statusOK:
+		if reply == nil {
+			return nil
+		}
+		return c.cfg.Decode(resp.payload, reply)
+	case statusUnknownMethod:
+		return fmt.Errorf("%w: %s", ErrUnknownMethod, method)
+	default:
+		return &ServerError{Method: method, Message: string(resp.payload)}
+	}
+}
+
+// Close closes the connection, pending calls fail with ErrShutdown.
+func (c *Client) Close() error {
+	err := c.conn.Close()
+	c.shutdown(ErrShutdown)
+	return err
+}
+
+// Err returns the error that stopped the client, if any.
+func (c *Client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// loop reads the responses and delivers them to the pending calls.
+func (c *Client) loop() {
+	for {
+		msg, err := c.conn.ReadMessage()
+		if err != nil {
+			c.shutdown(err)
+			return
+		}
+
+		id, data, err := parseHeader(msg, kindResponse)
+		if err != nil || len(data) == 0 {
+			c.shutdown(ErrInvalidMessage)
+			return
+		}
+
+		c.mu.Lock()
+		ch, ok := c.pending[id]
+		delete(c.pending, id)
+		c.mu.Unlock()
+
+		if ok {
+			ch <- response{
+				status:  data[0],
+				payload: append([]byte(nil), data[1:]...), // The message is reused by the next read.
+			}
+		}
+	}
+}
+
+func (c *Client) remove(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// shutdown stops the client with err, waking up all the pending calls.
+func (c *Client) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	if isClosed(err) {
+		err = ErrShutdown
+	}
+	c.err = err
+	c.pending = map[uint64]chan response{}
+	close(c.done)
+
+	c.conn.Close()
+}
+
+////////////////////////////
+//                        //
+// Server                 //
+//                        //
+////////////////////////////
+
+type (
+	// A Handler handles the calls of a method. The returned value is encoded as the reply.
+	// Handlers are invoked concurrently.
+	Handler func(ctx context.Context, req *Request) (any, error)
+
+	// A Request is a call received by a Server.
+	Request struct {
+		Method  string
+		payload []byte
+		decode  blockio.Decode
+	}
+
+	// A Server dispatches the calls received over connections to the handlers registered for their method.
+	Server struct {
+		cfg Config
+
+		mu       sync.RWMutex
+		handlers map[string]Handler
+	}
+)
+
+// Decode decodes the arguments of the call in v.
+func (r *Request) Decode(v any) error {
+	return r.decode(r.payload, v)
+}
+
+// NewServer returns a new Server.
+func NewServer(cfg Config) *Server {
+	cfg.normalize()
+
+	return &Server{
+		cfg:      cfg,
+		handlers: map[string]Handler{},
+	}
+}
+
+// Handle registers h for the given method.
+func (s *Server) Handle(method string, h Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.handlers[method] = h
+}
+
+// HandleFunc registers fn for the given method, the arguments being decoded in a new Req.
+func HandleFunc[Req, Resp any](s *Server, method string, fn func(ctx context.Context, req *Req) (Resp, error)) {
+	s.Handle(method, func(ctx context.Context, r *Request) (any, error) {
+		req := new(Req)
+		if err := r.Decode(req); err != nil {
+			return nil, err
+		}
+		return fn(ctx, req)
+	})
+}
+
+// Serve accepts connections from l and serves each of them in a new goroutine.
+// It returns the error of l.Accept.
+func (s *Server) Serve(l *blockio.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+
+		go func() {
+			defer conn.Close()
+			s.ServeConn(conn) // Errors only concern this connection.
+		}()
+	}
+}
+
+// ServeConn serves the calls received over conn until it is closed, it does not close conn.
+// The context of the handlers is canceled once conn fails. It returns nil when the connection is closed.
+func (s *Server) ServeConn(conn *blockio.Conn) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	for {
+		msg, err := conn.ReadMessage()
+		if err != nil {
+			if isClosed(err) {
+				return nil
+			}
+			return err
+		}
+
+		id, data, err := parseHeader(msg, kindRequest)
+		if err != nil {
+			return err
+		}
+		l, n := binary.Uvarint(data)
+		if n <= 0 || uint64(len(data)-n) < l {
+			return ErrInvalidMessage
+		}
+
+		req := &Request{
+			Method:  string(data[n : n+int(l)]),
+			payload: append([]byte(nil), data[n+int(l):]...), // The message is reused by the next read.
+			decode:  s.cfg.Decode,
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.call(ctx, conn, id, req)
+		}()
+	}
+}
+
+// call invokes the handler of req and writes the response.
+func (s *Server) call(ctx context.Context, conn *blockio.Conn, id uint64, req *Request) {
+	s.mu.RLock()
+	h, ok := s.handlers[req.Method]
+	s.mu.RUnlock()
+
+	if !ok {
+		s.respond(conn, id, statusUnknownMethod, nil)
+		return
+	}
+
+	if s.cfg.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+		defer cancel()
+	}
+
+	v, err := h(ctx, req)
+	if err != nil {
+		s.respond(conn, id, statusError, []byte(err.Error()))
+		return
+	}
+
+	payload, err := s.cfg.Encode(v)
+	if err != nil {
+		s.respond(conn, id, statusError, []byte(err.Error()))
+		return
+	}
+
+	if err = s.respond(conn, id, statusOK, payload); errors.Is(err, blockio.ErrMessageTooLarge) {
+		s.respond(conn, id, statusError, []byte(err.Error()))
+	}
+}
+
+// respond writes a response. A connection failure is reported by ServeConn.
+func (s *Server) respond(conn *blockio.Conn, id uint64, status byte, payload []byte) error {
+	msg := appendHeader(make([]byte, 0, headerSize+1+len(payload)), kindResponse, id)
+	msg = append(msg, status)
+	msg = append(msg, payload...)
+
+	return conn.WriteMessage(msg)
+}
@@ -0,0 +1,216 @@
+package rpc_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mdouchement/blockio"
+	"github.com/mdouchement/blockio/codec"
+	"github.com/mdouchement/blockio/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+type args struct {
+	A, B int
+}
+
+func newPair(t *testing.T, cfg rpc.Config, s *rpc.Server) *rpc.Client {
+	c1, c2 := net.Pipe()
+
+	client, err := blockio.NewConn(c1, blockio.ConnConfig{})
+	assert.NoError(t, err)
+	server, err := blockio.NewConn(c2, blockio.ConnConfig{})
+	assert.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert.NoError(t, s.ServeConn(server))
+	}()
+
+	c := rpc.NewClient(client, cfg)
+	t.Cleanup(func() {
+		c.Close()
+		server.Close()
+		<-done
+	})
+	return c
+}
+
+func TestClient_Call(t *testing.T) {
+	s := rpc.NewServer(rpc.Config{})
+	rpc.HandleFunc(s, "add", func(ctx context.Context, req *args) (int, error) {
+		return req.A + req.B, nil
+	})
+	rpc.HandleFunc(s, "div", func(ctx context.Context, req *args) (int, error) {
+		if req.B == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return req.A / req.B, nil
+	})
+	s.Handle("noop", func(ctx context.Context, req *rpc.Request) (any, error) {
+		return nil, nil
+	})
+
+	c := newPair(t, rpc.Config{}, s)
+
+	//
+	// Concurrent calls
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			var sum int
+			assert.NoError(t, c.Call(context.Background(), "add", args{A: i, B: 1}, &sum))
+			assert.Equal(t, i+1, sum)
+		}(i)
+	}
+	wg.Wait()
+
+	//
+	// Nil reply
+
+	assert.NoError(t, c.Call(context.Background(), "noop", nil, nil))
+
+	//
+	// Error response
+
+	err := c.Call(context.Background(), "div", args{A: 1}, new(int))
+	var serr *rpc.ServerError
+	assert.ErrorAs(t, err, &serr)
+	assert.Equal(t, "div", serr.Method)
+	assert.Equal(t, "division by zero", serr.Message)
+
+	//
+	// Unknown method
+
+	err = c.Call(context.Background(), "mul", args{A: 1, B: 2}, new(int))
+	assert.ErrorIs(t, err, rpc.ErrUnknownMethod)
+
+	// The client is still usable.
+	var div int
+	assert.NoError(t, c.Call(context.Background(), "div", args{A: 6, B: 2}, &div))
+	assert.Equal(t, 3, div)
+}
+
+func TestClient_CallCodec(t *testing.T) {
+	cfg := rpc.Config{
+		Encode: codec.String().Encode,
+		Decode: codec.String().Decode,
+	}
+
+	s := rpc.NewServer(cfg)
+	rpc.HandleFunc(s, "hello", func(ctx context.Context, name *string) (string, error) {
+		return fmt.Sprintf("hello %s", *name), nil
+	})
+
+	c := newPair(t, cfg, s)
+
+	var reply string
+	assert.NoError(t, c.Call(context.Background(), "hello", "world", &reply))
+	assert.Equal(t, "hello world", reply)
+
+	assert.ErrorIs(t, c.Call(context.Background(), "hello", 42, &reply), codec.ErrUnsupportedType)
+}
+
+func TestClient_CallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	s := rpc.NewServer(rpc.Config{Timeout: 20 * time.Millisecond})
+	s.Handle("block", func(ctx context.Context, req *rpc.Request) (any, error) {
+		<-release // Ignores the server timeout.
+		return nil, nil
+	})
+	s.Handle("wait", func(ctx context.Context, req *rpc.Request) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	//
+	// Context deadline
+
+	c := newPair(t, rpc.Config{}, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, c.Call(ctx, "block", nil, nil), context.DeadlineExceeded)
+
+	//
+	// Server timeout
+
+	err := c.Call(context.Background(), "wait", nil, nil)
+	var serr *rpc.ServerError
+	if assert.ErrorAs(t, err, &serr) {
+		assert.Equal(t, context.DeadlineExceeded.Error(), serr.Message)
+	}
+
+	//
+	// Client timeout
+
+	c = newPair(t, rpc.Config{Timeout: 20 * time.Millisecond}, s)
+	assert.ErrorIs(t, c.Call(context.Background(), "block", nil, nil), context.DeadlineExceeded)
+}
+
+func TestClient_Close(t *testing.T) {
+	started := make(chan struct{})
+
+	s := rpc.NewServer(rpc.Config{})
+	s.Handle("wait", func(ctx context.Context, req *rpc.Request) (any, error) {
+		close(started)
+		<-ctx.Done() // Canceled once the connection is closed.
+		return nil, ctx.Err()
+	})
+
+	c := newPair(t, rpc.Config{}, s)
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- c.Call(context.Background(), "wait", nil, nil)
+	}()
+	<-started
+
+	assert.NoError(t, c.Close())
+	assert.ErrorIs(t, <-errc, rpc.ErrShutdown)
+	assert.ErrorIs(t, c.Call(context.Background(), "wait", nil, nil), rpc.ErrShutdown)
+	assert.ErrorIs(t, c.Err(), rpc.ErrShutdown)
+}
+
+func TestServer_Serve(t *testing.T) {
+	l, err := blockio.Listen("tcp", "127.0.0.1:0", blockio.ConnConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := rpc.NewServer(rpc.Config{})
+	rpc.HandleFunc(s, "add", func(ctx context.Context, req *args) (int, error) {
+		return req.A + req.B, nil
+	})
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Serve(l)
+	}()
+
+	for i := 0; i < 3; i++ {
+		conn, err := blockio.Dial("tcp", l.Addr().String(), blockio.ConnConfig{})
+		assert.NoError(t, err)
+
+		c := rpc.NewClient(conn, rpc.Config{})
+		var sum int
+		assert.NoError(t, c.Call(context.Background(), "add", args{A: i, B: i}, &sum))
+		assert.Equal(t, 2*i, sum)
+		assert.NoError(t, c.Close())
+	}
+
+	assert.NoError(t, l.Close())
+	assert.ErrorIs(t, <-served, net.ErrClosed)
+}
@@ -0,0 +1,92 @@
+package blockio
+
+import (
+	"errors"
+	"io"
+)
+
+////////////////////////////
+//                        //
+// BlockScanner           //
+//                        //
+////////////////////////////
+
+// A BlockScanner reads successive raw blocks from a Decoder, in the manner of bufio.Scanner.
+type BlockScanner struct {
+	d    *Decoder
+	data []byte
+	err  error
+}
+
+// NewBlockScanner returns a new BlockScanner reading from d.
+func NewBlockScanner(d *Decoder) *BlockScanner {
+	return &BlockScanner{d: d}
+}
+
+// Scan advances to the next block, which will then be available through the Bytes method.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (s *BlockScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.data, s.err = s.d.ReadBlock()
+	return s.err == nil
+}
+
+// Bytes returns the most recent block read by a call to Scan.
+// The underlying array may point to data that will be overwritten by a subsequent call to Scan.
+func (s *BlockScanner) Bytes() []byte {
+	return s.data
+}
+
+// Err returns the first non-EOF error that was encountered by the BlockScanner.
+func (s *BlockScanner) Err() error {
+	if errors.Is(s.err, io.EOF) {
+		return nil
+	}
+	return s.err
+}
+
+////////////////////////////
+//                        //
+// Scanner                //
+//                        //
+////////////////////////////
+
+// A Scanner reads successive values from a TypedDecoder, in the manner of bufio.Scanner.
+type Scanner[T any] struct {
+	d   *TypedDecoder[T]
+	v   T
+	err error
+}
+
+// NewScanner returns a new Scanner reading from d.
+func NewScanner[T any](d *TypedDecoder[T]) *Scanner[T] {
+	return &Scanner[T]{d: d}
+}
+
+// Scan advances to the next value, which will then be available through the Value method.
+// It returns false when the scan stops, either by reaching the end of the input or an error.
+func (s *Scanner[T]) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+
+	s.v, s.err = s.d.Read()
+	return s.err == nil
+}
+
+// Value returns the most recent value decoded by a call to Scan.
+// Each value is freshly decoded and can be retained.
+func (s *Scanner[T]) Value() T {
+	return s.v
+}
+
+// Err returns the first non-EOF error that was encountered by the Scanner.
+func (s *Scanner[T]) Err() error {
+	if errors.Is(s.err, io.EOF) {
+		return nil
+	}
+	return s.err
+}
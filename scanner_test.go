@@ -0,0 +1,36 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockScanner_Scan(t *testing.T) {
+	buf := bytes.NewBuffer([]byte{4, 'd', 'a', 't', 'a', 5, 'd', 'a', 't', 'u', 'm'})
+	scanner := blockio.NewBlockScanner(blockio.NewBlock8Decoder(buf, json.Unmarshal))
+
+	var blocks []string
+	for scanner.Scan() {
+		blocks = append(blocks, string(scanner.Bytes()))
+	}
+	assert.NoError(t, scanner.Err())
+	assert.Equal(t, []string{"data", "datum"}, blocks)
+}
+
+func TestScanner_Scan(t *testing.T) {
+	buf := bytes.NewBufferString("\x011\x012\x01a")
+	decoder := blockio.NewTypedDecoder(blockio.NewReader8(buf), blockio.DecodeOf[int](json.Unmarshal), make([]byte, blockio.MaxBlock8))
+	scanner := blockio.NewScanner(decoder)
+
+	var values []int
+	for scanner.Scan() {
+		values = append(values, scanner.Value())
+	}
+	assert.Equal(t, []int{1, 2}, values)
+	assert.Error(t, scanner.Err())
+	assert.False(t, scanner.Scan())
+}
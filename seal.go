@@ -0,0 +1,242 @@
+package blockio
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Versions of the sealed stream header.
+const (
+	sealVersion         byte = iota + 1 // magic | version | file ID
+	sealVersionKeyID                    // magic | version | file ID | key ID length | key ID
+	sealVersionEnvelope                 // magic | version | file ID | key ID length | key ID | wrapped data key
+)
+
+const sealFileID = 16
+
+// Flags of the first byte of sealed blocks.
+const (
+	sealData  byte = iota // Regular block.
+	sealFinal             // Empty block marking the end of the stream.
+)
+
+var (
+	// ErrAuthentication is returned when a block has been tampered, reordered or does not belong to the stream.
+	ErrAuthentication = errors.New("block authentication failed")
+	// ErrTruncated is returned when a stream ends before its final block.
+	ErrTruncated = errors.New("block stream truncated")
+	// ErrClosed is returned when writing to a closed writer.
+	ErrClosed = errors.New("writer closed")
+)
+
+var sealMagic = []byte("BIOE")
+
+// A BlockError records an error and the index of the block that caused it.
+type BlockError struct {
+	Index uint64
+	Err   error
+}
+
+func (e *BlockError) Error() string {
+	return fmt.Sprintf("block %d: %v", e.Index, e.Err)
+}
+
+func (e *BlockError) Unwrap() error {
+	return e.Err
+}
+
+type sealWriter struct {
+	dst     io.Writer
+	aead    cipher.AEAD
+	header  []byte
+	written bool
+	closed  bool
+	ordinal uint64
+	buf     []byte
+	ad      []byte
+}
+
+// NewSealWriter returns a new writer that encrypts and authenticates each block with aead (e.g. AES-GCM) before writing it to w.
+// Provided w must be a block writer.
+//
+// A header block holding a random file ID is written before the first block.
+// Each block is sealed with a random nonce, the header, its ordinal and its flag as additional data,
+// so blocks cannot be modified, reordered, dropped or moved to another stream without being detected.
+// Close must be called to write the final block, without it readers report the stream as truncated.
+func NewSealWriter(w io.Writer, aead cipher.AEAD) (io.WriteCloser, error) {
+	header, err := newSealHeader(sealVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	return newSealWriter(w, aead, header), nil
+}
+
+// newSealHeader returns the beginning of a header with a random file ID.
+func newSealHeader(version byte) ([]byte, error) {
+	header := make([]byte, len(sealMagic)+1+sealFileID)
+	copy(header, sealMagic)
+	header[len(sealMagic)] = version
+	if _, err := rand.Read(header[len(sealMagic)+1:]); err != nil {
+		return nil, err
+	}
+
+	return header, nil
+}
+
+func newSealWriter(w io.Writer, aead cipher.AEAD, header []byte) *sealWriter {
+	return &sealWriter{
+		dst:    w,
+		aead:   aead,
+		header: header,
+	}
+}
+
+func (w *sealWriter) Write(block []byte) (n int, err error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if err = w.seal(sealData, block); err != nil {
+		return 0, err
+	}
+	return len(block), nil
+}
+
+// Close writes the final block. It does not close the underlying writer.
+func (w *sealWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+
+	return w.seal(sealFinal, nil)
+}
+
+func (w *sealWriter) seal(flag byte, block []byte) error {
+	if !w.written {
+		if _, err := w.dst.Write(w.header); err != nil {
+			return err
+		}
+		w.written = true
+	}
+
+	ns := w.aead.NonceSize()
+	w.buf = append(w.buf[:0], flag)
+	w.buf = append(w.buf, make([]byte, ns)...)
+	nonce := w.buf[1 : 1+ns]
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	w.ad = sealAD(w.ad, w.header, w.ordinal, flag)
+	w.buf = w.aead.Seal(w.buf, nonce, block, w.ad)
+
+	if _, err := w.dst.Write(w.buf); err != nil {
+		return err
+	}
+
+	w.ordinal++
+	return nil
+}
+
+// sealAD returns the additional data of a sealed block.
+func sealAD(ad, header []byte, ordinal uint64, flag byte) []byte {
+	var o [8]byte
+	binary.BigEndian.PutUint64(o[:], ordinal)
+
+	ad = append(ad[:0], header...)
+	ad = append(ad, o[:]...)
+	return append(ad, flag)
+}
+
+type openReader struct {
+	src     io.Reader
+	buf     []byte
+	aead    cipher.AEAD
+	open    func(header []byte) (cipher.AEAD, error)
+	header  []byte
+	ordinal uint64
+	final   bool
+	ad      []byte
+}
+
+// NewOpenReader returns a new reader that decrypts and authenticates blocks of size up to size
+// written by a seal writer to r.
+// Provided r must be a block reader.
+// Tampered blocks are reported by a *BlockError wrapping ErrAuthentication,
+// and a stream missing its final block by a *BlockError wrapping ErrTruncated.
+func NewOpenReader(r io.Reader, size int, aead cipher.AEAD) io.Reader {
+	return newOpenReader(r, size, func(header []byte) (cipher.AEAD, error) {
+		if len(header) != len(sealMagic)+1+sealFileID || header[len(sealMagic)] != sealVersion {
+			return nil, ErrInvalidHeader
+		}
+		return aead, nil
+	})
+}
+
+func newOpenReader(r io.Reader, size int, open func(header []byte) (cipher.AEAD, error)) *openReader {
+	return &openReader{
+		src:  r,
+		buf:  make([]byte, size),
+		open: open,
+	}
+}
+
+func (r *openReader) Read(p []byte) (n int, err error) {
+	if r.aead == nil {
+		n, err = r.src.Read(r.buf[:cap(r.buf)])
+		if err != nil {
+			return 0, err
+		}
+		if !bytes.HasPrefix(r.buf[:n], sealMagic) {
+			return 0, ErrInvalidHeader
+		}
+
+		r.header = append([]byte(nil), r.buf[:n]...)
+		if r.aead, err = r.open(r.header); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err = r.src.Read(r.buf[:cap(r.buf)])
+	if errors.Is(err, io.EOF) && !r.final {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrTruncated}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if r.final {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrAuthentication} // Data after the final block.
+	}
+
+	ns := r.aead.NonceSize()
+	if n < 1+ns+r.aead.Overhead() {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrAuthentication}
+	}
+	flag := r.buf[0]
+	nonce := r.buf[1 : 1+ns]
+	ciphertext := r.buf[1+ns : n]
+
+	if len(ciphertext)-r.aead.Overhead() > cap(p) {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	r.ad = sealAD(r.ad, r.header, r.ordinal, flag)
+	plaintext, err := r.aead.Open(p[:0], nonce, ciphertext, r.ad)
+	if err != nil {
+		return 0, &BlockError{Index: r.ordinal, Err: ErrAuthentication}
+	}
+	r.ordinal++
+
+	if flag == sealFinal {
+		r.final = true
+		return r.Read(p) // Expect io.EOF.
+	}
+	return len(plaintext), nil
+}
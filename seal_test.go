@@ -0,0 +1,174 @@
+package blockio_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func newAEAD(t *testing.T, key byte) cipher.AEAD {
+	t.Helper()
+
+	block, err := aes.NewCipher(bytes.Repeat([]byte{key}, 32))
+	assert.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	assert.NoError(t, err)
+	return aead
+}
+
+// rawBlocks splits a stream of Block16 in raw frames.
+func rawBlocks(t *testing.T, data []byte) [][]byte {
+	t.Helper()
+
+	var blocks [][]byte
+	r := blockio.NewReader16(bytes.NewReader(data))
+	buf := make([]byte, blockio.MaxBlock16)
+	for {
+		n, err := r.Read(buf)
+		if err == io.EOF {
+			return blocks
+		}
+		assert.NoError(t, err)
+		blocks = append(blocks, append([]byte(nil), buf[:n]...))
+	}
+}
+
+// joinBlocks writes raw frames as a stream of Block16.
+func joinBlocks(t *testing.T, blocks ...[]byte) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := blockio.NewWriter16(&buf)
+	for _, block := range blocks {
+		_, err := w.Write(block)
+		assert.NoError(t, err)
+	}
+	return buf.Bytes()
+}
+
+func readAll(r io.Reader, size int) ([]string, error) {
+	var blocks []string
+	buf := make([]byte, size)
+	for {
+		n, err := r.Read(buf)
+		if err == io.EOF {
+			return blocks, nil
+		}
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, string(buf[:n]))
+	}
+}
+
+func TestSealWriter_Write(t *testing.T) {
+	aead := newAEAD(t, 42)
+
+	var buf bytes.Buffer
+	w, err := blockio.NewSealWriter(blockio.NewWriter16(&buf), aead)
+	assert.NoError(t, err)
+
+	for _, block := range []string{"data", "datum", "last"} {
+		n, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+		assert.Equal(t, len(block), n)
+	}
+	assert.NoError(t, w.Close())
+	_, err = w.Write([]byte("data"))
+	assert.ErrorIs(t, err, blockio.ErrClosed)
+
+	data := buf.Bytes()
+	assert.NotContains(t, string(data), "datum")
+
+	blocks, err := readAll(blockio.NewOpenReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, aead), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "datum", "last"}, blocks)
+
+	//
+	// Wrong key
+
+	_, err = readAll(blockio.NewOpenReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, newAEAD(t, 24)), 64)
+	assert.ErrorIs(t, err, blockio.ErrAuthentication)
+}
+
+func TestOpenReader_Read(t *testing.T) {
+	aead := newAEAD(t, 42)
+
+	seal := func() [][]byte {
+		var buf bytes.Buffer
+		w, err := blockio.NewSealWriter(blockio.NewWriter16(&buf), aead)
+		assert.NoError(t, err)
+		for _, block := range []string{"data", "datum", "last"} {
+			_, err = w.Write([]byte(block))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, w.Close())
+		return rawBlocks(t, buf.Bytes())
+	}
+	blocks := seal() // header, data, datum, last, final
+
+	for _, tt := range []struct {
+		name   string
+		blocks [][]byte
+		err    error
+		index  uint64
+		read   []string
+	}{
+		{name: "reordered", blocks: [][]byte{blocks[0], blocks[2], blocks[1], blocks[3], blocks[4]}, err: blockio.ErrAuthentication, index: 0},
+		{name: "dropped", blocks: [][]byte{blocks[0], blocks[1], blocks[3], blocks[4]}, err: blockio.ErrAuthentication, index: 1, read: []string{"data"}},
+		{name: "duplicated", blocks: [][]byte{blocks[0], blocks[1], blocks[1], blocks[2], blocks[3], blocks[4]}, err: blockio.ErrAuthentication, index: 1, read: []string{"data"}},
+		{name: "truncated", blocks: blocks[:4], err: blockio.ErrTruncated, index: 3, read: []string{"data", "datum", "last"}},
+		{name: "trailing", blocks: append(append([][]byte{}, blocks...), blocks[1]), err: blockio.ErrAuthentication, index: 4, read: []string{"data", "datum", "last"}},
+		{name: "other stream", blocks: [][]byte{seal()[0], blocks[1], blocks[2], blocks[3], blocks[4]}, err: blockio.ErrAuthentication, index: 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := blockio.NewOpenReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, tt.blocks...))), blockio.MaxBlock16, aead)
+			read, err := readAll(r, 64)
+			assert.ErrorIs(t, err, tt.err)
+
+			var berr *blockio.BlockError
+			if assert.ErrorAs(t, err, &berr) {
+				assert.Equal(t, tt.index, berr.Index)
+			}
+			assert.Equal(t, tt.read, read)
+		})
+	}
+
+	//
+	// Modified block
+
+	tampered := append([]byte(nil), blocks[2]...)
+	tampered[len(tampered)-1] ^= 0xFF
+	_, err := readAll(blockio.NewOpenReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks[0], blocks[1], tampered))), blockio.MaxBlock16, aead), 64)
+	assert.ErrorIs(t, err, blockio.ErrAuthentication)
+
+	//
+	// Too small buffer
+
+	_, err = readAll(blockio.NewOpenReader(blockio.NewReader16(bytes.NewReader(joinBlocks(t, blocks...))), blockio.MaxBlock16, aead), 4)
+	assert.ErrorIs(t, err, blockio.ErrBlockSizeTooSmall)
+}
+
+func TestSeal_Encoder(t *testing.T) {
+	aead := newAEAD(t, 42)
+
+	var buf bytes.Buffer
+	w, err := blockio.NewSealWriter(blockio.NewWriter16(&buf), aead)
+	assert.NoError(t, err)
+
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write("secret"))
+	assert.NoError(t, w.Close())
+
+	decoder := blockio.NewBlockDecoder(blockio.NewOpenReader(blockio.NewReader16(&buf), blockio.MaxBlock16, aead), json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "secret", v)
+	assert.ErrorIs(t, decoder.Read(&v), io.EOF)
+}
@@ -0,0 +1,152 @@
+package blockio
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+)
+
+var (
+	// ErrUnsigned is returned when a stream required to be signed has no signature.
+	ErrUnsigned = errors.New("stream not signed")
+	// ErrSignature is returned when the signature of a stream is invalid or made by an untrusted key.
+	ErrSignature = errors.New("invalid stream signature")
+)
+
+var signMagic = []byte("BIOS")
+
+// signTrailerSize is the size of the signature trailer: magic | public key | signature.
+var signTrailerSize = len(signMagic) + ed25519.PublicKeySize + ed25519.SignatureSize
+
+// blockDigest feeds the data of a block to the digest h.
+// The length is included so the digest covers the framing.
+func blockDigest(h hash.Hash, data []byte) {
+	var l [binary.MaxVarintLen64]byte
+	h.Write(l[:binary.PutUvarint(l[:], uint64(len(data)))])
+	h.Write(data)
+}
+
+// A SignWriter writes blocks as is and signs them with Ed25519 in a trailer block.
+type SignWriter struct {
+	dst    io.Writer
+	digest hash.Hash
+	closed bool
+}
+
+// NewSignWriter returns a new SignWriter writing blocks to w.
+// Provided w must be a block writer.
+func NewSignWriter(w io.Writer) *SignWriter {
+	return &SignWriter{
+		dst:    w,
+		digest: sha512.New(),
+	}
+}
+
+func (w *SignWriter) Write(block []byte) (n int, err error) {
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	if _, err = w.dst.Write(block); err != nil {
+		return 0, err
+	}
+
+	blockDigest(w.digest, block)
+	return len(block), nil
+}
+
+// Sign finalizes the stream by writing a trailer holding the signature of all the written blocks made with key
+// and its public key. It does not close the underlying writer.
+func (w *SignWriter) Sign(key ed25519.PrivateKey) error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+
+	signature := ed25519.Sign(key, w.digest.Sum(nil))
+
+	trailer := make([]byte, 0, signTrailerSize)
+	trailer = append(trailer, signMagic...)
+	trailer = append(trailer, key.Public().(ed25519.PublicKey)...)
+	trailer = append(trailer, signature...)
+
+	_, err := w.dst.Write(trailer)
+	return err
+}
+
+// A VerifyReader reads the blocks written by a SignWriter and verifies their signature.
+type VerifyReader struct {
+	tr      *trailerReader
+	digest  hash.Hash
+	trusted []ed25519.PublicKey
+	signer  ed25519.PublicKey
+}
+
+// NewVerifyReader returns a new VerifyReader reading blocks of size up to size from r.
+// Provided r must be a block reader.
+//
+// Blocks are yielded before being verified; once all the blocks are read,
+// io.EOF is returned only if the stream is signed by one of the trusted keys, ErrSignature otherwise.
+// An unsigned stream is reported by ErrUnsigned when required, or fully yielded otherwise.
+// Use VerifySignature to verify a stream before reading it.
+func NewVerifyReader(r io.Reader, size int, trusted []ed25519.PublicKey, required bool) *VerifyReader {
+	vr := &VerifyReader{
+		digest:  sha512.New(),
+		trusted: trusted,
+	}
+
+	vr.tr = newTrailerReader(r, size, func(data []byte) {
+		blockDigest(vr.digest, data)
+	}, func(data []byte) (bool, error) {
+		if len(data) != signTrailerSize || !bytes.HasPrefix(data, signMagic) {
+			if required {
+				return true, ErrUnsigned
+			}
+			return false, nil // Yielded as a regular block, digested by the block callback.
+		}
+
+		public := ed25519.PublicKey(data[len(signMagic) : len(signMagic)+ed25519.PublicKeySize])
+		signature := data[len(signMagic)+ed25519.PublicKeySize:]
+
+		for _, key := range trusted {
+			if key.Equal(public) && ed25519.Verify(key, vr.digest.Sum(nil), signature) {
+				vr.signer = key
+				return true, nil
+			}
+		}
+		return true, ErrSignature
+	})
+
+	return vr
+}
+
+func (r *VerifyReader) Read(p []byte) (n int, err error) {
+	return r.tr.Read(p)
+}
+
+// Signer returns the trusted key that signed the stream once all the blocks have been read, nil otherwise.
+func (r *VerifyReader) Signer() ed25519.PublicKey {
+	return r.signer
+}
+
+// VerifySignature reads all the blocks of size up to size from r and verifies that they are signed by one of the trusted keys.
+// Provided r must be a block reader.
+// It returns the trusted key that signed the stream.
+func VerifySignature(r io.Reader, size int, trusted []ed25519.PublicKey) (ed25519.PublicKey, error) {
+	vr := NewVerifyReader(r, size, trusted, true)
+	buf := make([]byte, size)
+
+	for {
+		_, err := vr.Read(buf)
+		if errors.Is(err, io.EOF) {
+			return vr.signer, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
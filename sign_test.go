@@ -0,0 +1,117 @@
+package blockio_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWriter_Sign(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	other, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := blockio.NewSignWriter(blockio.NewWriter16(&buf))
+	for _, block := range []string{"data", "datum", "last"} {
+		n, err := w.Write([]byte(block))
+		assert.NoError(t, err)
+		assert.Equal(t, len(block), n)
+	}
+	assert.NoError(t, w.Sign(private))
+	assert.ErrorIs(t, w.Sign(private), blockio.ErrClosed)
+	_, err = w.Write([]byte("data"))
+	assert.ErrorIs(t, err, blockio.ErrClosed)
+	data := buf.Bytes()
+
+	//
+	// Verify while reading
+
+	r := blockio.NewVerifyReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, []ed25519.PublicKey{other, public}, true)
+	blocks, err := readAll(r, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "datum", "last"}, blocks)
+	assert.Equal(t, public, r.Signer())
+
+	//
+	// Verify before reading
+
+	signer, err := blockio.VerifySignature(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, []ed25519.PublicKey{public})
+	assert.NoError(t, err)
+	assert.Equal(t, public, signer)
+
+	//
+	// Untrusted
+
+	_, err = blockio.VerifySignature(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, []ed25519.PublicKey{other})
+	assert.ErrorIs(t, err, blockio.ErrSignature)
+
+	//
+	// Tampered
+
+	raw := rawBlocks(t, data)
+	raw[1] = []byte("dotum")
+	_, err = blockio.VerifySignature(blockio.NewReader16(bytes.NewReader(joinBlocks(t, raw...))), blockio.MaxBlock16, []ed25519.PublicKey{public})
+	assert.ErrorIs(t, err, blockio.ErrSignature)
+
+	//
+	// Dropped block
+
+	raw = rawBlocks(t, data)
+	_, err = blockio.VerifySignature(blockio.NewReader16(bytes.NewReader(joinBlocks(t, raw[0], raw[2], raw[3]))), blockio.MaxBlock16, []ed25519.PublicKey{public})
+	assert.ErrorIs(t, err, blockio.ErrSignature)
+}
+
+func TestVerifyReader_Unsigned(t *testing.T) {
+	public, _, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	data := joinBlocks(t, []byte("data"), []byte("datum"))
+
+	r := blockio.NewVerifyReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, []ed25519.PublicKey{public}, true)
+	blocks, err := readAll(r, 64)
+	assert.ErrorIs(t, err, blockio.ErrUnsigned)
+	assert.Equal(t, []string{"data"}, blocks)
+
+	r = blockio.NewVerifyReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, []ed25519.PublicKey{public}, false)
+	blocks, err = readAll(r, 64)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data", "datum"}, blocks)
+	assert.Nil(t, r.Signer())
+
+	//
+	// Truncated first block
+
+	r = blockio.NewVerifyReader(blockio.NewReader16(bytes.NewReader([]byte{0})), blockio.MaxBlock16, []ed25519.PublicKey{public}, false)
+	block := make([]byte, 64)
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF)
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.ErrUnexpectedEOF) // Sticky, no phantom block.
+
+	_, err = blockio.VerifySignature(blockio.NewReader16(bytes.NewReader(nil)), blockio.MaxBlock16, []ed25519.PublicKey{public})
+	assert.ErrorIs(t, err, blockio.ErrUnsigned)
+}
+
+func TestSign_Encoder(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	w := blockio.NewSignWriter(blockio.NewWriter16(&buf))
+	encoder := blockio.NewBlockEncoder(w, json.Marshal)
+	assert.NoError(t, encoder.Write("record"))
+	assert.NoError(t, w.Sign(private))
+
+	decoder := blockio.NewBlockDecoder(blockio.NewVerifyReader(blockio.NewReader16(&buf), blockio.MaxBlock16, []ed25519.PublicKey{public}, true), json.Unmarshal, make([]byte, blockio.MaxBlock16))
+	var v string
+	assert.NoError(t, decoder.Read(&v))
+	assert.Equal(t, "record", v)
+	assert.ErrorIs(t, decoder.Read(&v), io.EOF)
+}
@@ -0,0 +1,97 @@
+package blockio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+)
+
+// The split functions match the fixed-width headers of the block readers and writers.
+// There is no varint variant because the package has no varint-length framing to split.
+//
+// A block larger than the scanner's maximum token size (see bufio.Scanner.Buffer) can not be buffered,
+// the scan stops with bufio.ErrTooLong.
+
+// SplitBlock8 is a bufio.SplitFunc that returns the data of each Block8.
+func SplitBlock8(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return split(data, atEOF, 1, MaxBlock8)
+}
+
+// SplitBlock16 is a bufio.SplitFunc that returns the data of each Block16.
+// The scanner's buffer must be able to hold MaxBlock16+2 bytes to handle all the blocks.
+func SplitBlock16(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return split(data, atEOF, 2, MaxBlock16)
+}
+
+// SplitBlock24 is a bufio.SplitFunc that returns the data of each Block24.
+// The scanner's buffer must be able to hold MaxBlock24+3 bytes to handle all the blocks.
+func SplitBlock24(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return split(data, atEOF, 3, MaxBlock24)
+}
+
+// SplitBlock24Custom returns a bufio.SplitFunc that returns the data of each Block24 up to size.
+// A block declaring a length larger than size stops the scan with ErrSizeTooLarge.
+// The scanner's buffer must be able to hold size+3 bytes.
+func SplitBlock24Custom(size int) (bufio.SplitFunc, error) {
+	if size > MaxBlock24 {
+		return nil, ErrSizeTooLarge
+	}
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		return split(data, atEOF, 3, size)
+	}, nil
+}
+
+// SplitBlock32 is a bufio.SplitFunc that returns the data of each Block32.
+// The scanner's buffer must be able to hold the largest block plus 4 bytes.
+func SplitBlock32(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	return split(data, atEOF, 4, MaxBlock32)
+}
+
+// SplitBlock32Custom returns a bufio.SplitFunc that returns the data of each Block32 up to size.
+// A block declaring a length larger than size stops the scan with ErrSizeTooLarge.
+// The scanner's buffer must be able to hold size+4 bytes.
+func SplitBlock32Custom(size int) (bufio.SplitFunc, error) {
+	if size > MaxBlock32 {
+		return nil, ErrSizeTooLarge
+	}
+
+	return func(data []byte, atEOF bool) (advance int, token []byte, err error) {
+		return split(data, atEOF, 4, size)
+	}, nil
+}
+
+// split extracts one block with a header of hsize bytes from data.
+func split(data []byte, atEOF bool, hsize, size int) (advance int, token []byte, err error) {
+	if len(data) < hsize {
+		if atEOF && len(data) > 0 {
+			return 0, nil, io.ErrUnexpectedEOF // Truncated header.
+		}
+		return 0, nil, nil // Request more data.
+	}
+
+	var n int
+	switch hsize {
+	case 1:
+		n = int(data[0])
+	case 2:
+		n = int(binary.BigEndian.Uint16(data))
+	case 3:
+		n = int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	case 4:
+		n = int(binary.BigEndian.Uint32(data))
+	}
+
+	if n > size {
+		return 0, nil, ErrSizeTooLarge
+	}
+
+	if len(data) < hsize+n {
+		if atEOF {
+			return 0, nil, io.ErrUnexpectedEOF // Truncated data.
+		}
+		return 0, nil, nil // Request more data.
+	}
+
+	return hsize + n, data[hsize : hsize+n], nil
+}
@@ -0,0 +1,89 @@
+package blockio_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitBlock(t *testing.T) {
+	blocks := []string{"data", "", "datum"}
+
+	for _, tt := range []struct {
+		name  string
+		split bufio.SplitFunc
+		nw    func(io.Writer) io.Writer
+	}{
+		{name: "8", split: blockio.SplitBlock8, nw: blockio.NewWriter8},
+		{name: "16", split: blockio.SplitBlock16, nw: blockio.NewWriter16},
+		{name: "24", split: blockio.SplitBlock24, nw: blockio.NewWriter24},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tt.nw(&buf)
+			for _, block := range blocks {
+				_, err := w.Write([]byte(block))
+				assert.NoError(t, err)
+			}
+
+			// One byte at a time to split headers across buffer boundaries.
+			scanner := bufio.NewScanner(iotest.OneByteReader(&buf))
+			scanner.Split(tt.split)
+
+			var tokens []string
+			for scanner.Scan() {
+				tokens = append(tokens, scanner.Text())
+			}
+			assert.NoError(t, scanner.Err())
+			assert.Equal(t, blocks, tokens)
+		})
+	}
+
+	//
+	// Truncated
+
+	scanner := bufio.NewScanner(bytes.NewReader([]byte{0, 4, 'd', 'a', 't', 'a', 0}))
+	scanner.Split(blockio.SplitBlock16)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, "data", scanner.Text())
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), io.ErrUnexpectedEOF)
+
+	scanner = bufio.NewScanner(bytes.NewReader([]byte{0, 5, 'd', 'a', 't', 'a'}))
+	scanner.Split(blockio.SplitBlock16)
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), io.ErrUnexpectedEOF)
+}
+
+func TestSplitBlockCustom(t *testing.T) {
+	_, err := blockio.SplitBlock24Custom(blockio.MaxBlock24 + 1)
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	split, err := blockio.SplitBlock32Custom(4)
+	assert.NoError(t, err)
+
+	scanner := bufio.NewScanner(bytes.NewReader([]byte{0, 0, 0, 4, 'd', 'a', 't', 'a', 0, 0, 0, 5, 'd', 'a', 't', 'u', 'm'}))
+	scanner.Buffer(make([]byte, 8), 8)
+	scanner.Split(split)
+	assert.True(t, scanner.Scan())
+	assert.Equal(t, "data", scanner.Text())
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), blockio.ErrSizeTooLarge)
+}
+
+func TestSplitBlock_TooLong(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := blockio.NewWriter8(&buf).Write([]byte("0123456789"))
+	assert.NoError(t, err)
+
+	scanner := bufio.NewScanner(&buf)
+	scanner.Buffer(make([]byte, 8), 8)
+	scanner.Split(blockio.SplitBlock8)
+	assert.False(t, scanner.Scan())
+	assert.ErrorIs(t, scanner.Err(), bufio.ErrTooLong)
+}
@@ -0,0 +1,161 @@
+package blockio
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Flags of the first byte of stream compressed blocks.
+const (
+	streamContinue byte = iota // The block continues the current flate stream.
+	streamRestart              // The block starts a new flate stream (restart point).
+)
+
+// ErrNoRestartPoint is returned when a stream compressed block cannot be decoded without its predecessors.
+var ErrNoRestartPoint = errors.New("block is not a restart point")
+
+type streamWriter struct {
+	dst     io.Writer
+	restart int
+	count   int
+	buf     bytes.Buffer
+	fw      *flate.Writer
+	hdr     [1 + binary.MaxVarintLen64]byte
+}
+
+// NewStreamCompressWriter returns a new writer that compresses all the blocks written to w in a single flate stream.
+// Provided w must be a block writer.
+// Each block ends on a sync flush so it is written as one block, while sharing the compression context of the previous ones.
+// Every restart blocks (0 means never), the flate stream is restarted so decoding can begin at this block.
+func NewStreamCompressWriter(w io.Writer, level, restart int) (io.Writer, error) {
+	sw := &streamWriter{
+		dst:     w,
+		restart: restart,
+	}
+
+	var err error
+	sw.fw, err = flate.NewWriter(&sw.buf, level)
+	if err != nil {
+		return nil, err
+	}
+
+	return sw, nil
+}
+
+func (w *streamWriter) Write(block []byte) (n int, err error) {
+	// Header: flag and uncompressed length.
+	w.hdr[0] = streamContinue
+	if w.count == 0 || (w.restart > 0 && w.count%w.restart == 0) {
+		w.hdr[0] = streamRestart
+		w.buf.Reset()
+		w.fw.Reset(&w.buf)
+	}
+	hsize := 1 + binary.PutUvarint(w.hdr[1:], uint64(len(block)))
+
+	w.buf.Reset()
+	w.buf.Write(w.hdr[:hsize])
+	if _, err = w.fw.Write(block); err != nil {
+		return 0, err
+	}
+	if err = w.fw.Flush(); err != nil {
+		return 0, err
+	}
+
+	if _, err = w.dst.Write(w.buf.Bytes()); err != nil {
+		return 0, err
+	}
+
+	w.count++
+	return len(block), nil
+}
+
+type streamReader struct {
+	src  io.Reader
+	buf  []byte
+	max  int
+	in   bytes.Buffer // Compressed data fed to the flate stream.
+	fr   io.ReadCloser
+	sync bool // Whether the reader is positioned on a valid flate stream.
+	skip bool
+}
+
+// NewStreamCompressReader returns a new reader that decompresses blocks of size up to size
+// written by a stream compress writer to r.
+// Provided r must be a block reader.
+// Blocks decompressing to more than max bytes are rejected with ErrDecompressedTooLarge
+// and the buffer given to Read must be able to hold max bytes.
+//
+// The first block read must be a restart point (e.g. the first block of the stream) otherwise ErrNoRestartPoint is returned.
+func NewStreamCompressReader(r io.Reader, size, max int) io.Reader {
+	return &streamReader{
+		src: r,
+		buf: make([]byte, size),
+		max: max,
+	}
+}
+
+// NewStreamCompressSeekReader is like NewStreamCompressReader but skips the blocks
+// until the first restart point. It allows to begin decoding at any block boundary.
+func NewStreamCompressSeekReader(r io.Reader, size, max int) io.Reader {
+	sr := NewStreamCompressReader(r, size, max).(*streamReader)
+	sr.skip = true
+	return sr
+}
+
+func (r *streamReader) Read(p []byte) (n int, err error) {
+	if cap(p) < r.max {
+		return 0, ErrBlockSizeTooSmall
+	}
+
+	for {
+		n, err = r.src.Read(r.buf[:cap(r.buf)])
+		if err != nil {
+			return 0, err
+		}
+		if n == 0 {
+			return 0, io.ErrUnexpectedEOF
+		}
+
+		if r.buf[0] == streamRestart {
+			r.in.Reset()
+			if r.fr == nil {
+				r.fr = flate.NewReader(&r.in)
+			} else if err = r.fr.(flate.Resetter).Reset(&r.in, nil); err != nil {
+				return 0, err
+			}
+			r.sync = true
+		}
+
+		if r.sync {
+			break
+		}
+		if !r.skip {
+			return 0, ErrNoRestartPoint
+		}
+	}
+
+	length, hsize := binary.Uvarint(r.buf[1:n])
+	if hsize <= 0 {
+		r.sync = false
+		return 0, ErrInvalidHeader
+	}
+	if length > uint64(r.max) {
+		r.sync = false // The stream cannot be continued without decoding this block.
+		return 0, ErrDecompressedTooLarge
+	}
+
+	r.in.Write(r.buf[1+hsize : n])
+	n, err = io.ReadFull(r.fr, p[:length])
+	if err != nil {
+		r.sync = false
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, err
+	}
+
+	return n, nil
+}
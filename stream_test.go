@@ -0,0 +1,83 @@
+package blockio_test
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreamCompressWriter_Write(t *testing.T) {
+	var records [][]byte
+	for i := 0; i < 100; i++ {
+		records = append(records, []byte(fmt.Sprintf(`{"identifier":%d,"description":"a record","enabled":true}`, i)))
+	}
+	records = append(records, []byte{}) // Empty block.
+	random := make([]byte, 512)
+	_, err := rand.Read(random)
+	assert.NoError(t, err)
+	records = append(records, random, []byte("last"))
+
+	var perBlock, stream bytes.Buffer
+
+	w, err := blockio.NewCompressWriter(blockio.NewWriter16(&perBlock), blockio.CompressionFlate, flate.BestCompression)
+	assert.NoError(t, err)
+	sw, err := blockio.NewStreamCompressWriter(blockio.NewWriter16(&stream), flate.BestCompression, 10)
+	assert.NoError(t, err)
+
+	for _, record := range records {
+		_, err = w.Write(record)
+		assert.NoError(t, err)
+		n, err := sw.Write(record)
+		assert.NoError(t, err)
+		assert.Equal(t, len(record), n)
+	}
+	assert.Less(t, stream.Len(), perBlock.Len())
+	data := stream.Bytes()
+
+	//
+	// From the beginning
+
+	r := blockio.NewStreamCompressReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, 1024)
+	block := make([]byte, 1024)
+	for _, record := range records {
+		n, err := r.Read(block)
+		assert.NoError(t, err)
+		assert.Equal(t, record, block[:n])
+	}
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, io.EOF)
+
+	//
+	// From the middle of the stream
+
+	br := blockio.NewReader16(bytes.NewReader(data))
+	raw := make([]byte, blockio.MaxBlock16)
+	for i := 0; i < 5; i++ {
+		_, err = br.Read(raw)
+		assert.NoError(t, err)
+	}
+
+	r = blockio.NewStreamCompressReader(br, blockio.MaxBlock16, 1024)
+	_, err = r.Read(block)
+	assert.ErrorIs(t, err, blockio.ErrNoRestartPoint)
+
+	r = blockio.NewStreamCompressSeekReader(br, blockio.MaxBlock16, 1024)
+	for _, record := range records[10:] { // Next restart point.
+		n, err := r.Read(block)
+		assert.NoError(t, err)
+		assert.Equal(t, record, block[:n])
+	}
+
+	//
+	// Too large
+
+	r = blockio.NewStreamCompressReader(blockio.NewReader16(bytes.NewReader(data)), blockio.MaxBlock16, 8)
+	_, err = r.Read(block[:8])
+	assert.ErrorIs(t, err, blockio.ErrDecompressedTooLarge)
+}
@@ -0,0 +1,79 @@
+package blockio
+
+import (
+	"errors"
+	"io"
+)
+
+// trailerReader reads blocks one block ahead so the last block of a stream can be handled as a trailer.
+type trailerReader struct {
+	src     io.Reader
+	cur     []byte
+	next    []byte
+	n       int // Length of next.
+	started bool
+	err     error // Sticky error, io.EOF once the trailer has been handled.
+
+	block func(data []byte)
+	// trailer handles the last block, data is nil when the stream is empty.
+	// It reports whether the block is a trailer, otherwise the block is yielded as a regular one.
+	trailer func(data []byte) (bool, error)
+}
+
+func newTrailerReader(r io.Reader, size int, block func([]byte), trailer func([]byte) (bool, error)) *trailerReader {
+	return &trailerReader{
+		src:     r,
+		cur:     make([]byte, size),
+		next:    make([]byte, size),
+		block:   block,
+		trailer: trailer,
+	}
+}
+
+func (r *trailerReader) Read(p []byte) (n int, err error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+
+	if !r.started {
+		r.started = true
+		r.n, err = r.src.Read(r.next[:cap(r.next)])
+		if errors.Is(err, io.EOF) {
+			_, err = r.trailer(nil)
+			return 0, r.fail(err)
+		}
+		if err != nil {
+			return 0, r.fail(err)
+		}
+	}
+
+	r.cur, r.next = r.next, r.cur
+	data := r.cur[:r.n]
+
+	r.n, err = r.src.Read(r.next[:cap(r.next)])
+	if errors.Is(err, io.EOF) {
+		trailer, err := r.trailer(data)
+		if trailer || err != nil {
+			return 0, r.fail(err)
+		}
+		r.err = io.EOF // Yield the last block then stop.
+	} else if err != nil {
+		return 0, r.fail(err) // The current block is lost.
+	}
+
+	if len(data) > cap(p) {
+		return 0, r.fail(ErrBlockSizeTooSmall)
+	}
+
+	r.block(data)
+	return copy(p[:cap(p)], data), nil
+}
+
+// fail records err as sticky error, io.EOF when err is nil.
+func (r *trailerReader) fail(err error) error {
+	if err == nil {
+		err = io.EOF
+	}
+	r.err = err
+	return err
+}
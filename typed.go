@@ -0,0 +1,146 @@
+package blockio
+
+import (
+	"io"
+)
+
+////////////////////////////
+//                        //
+// TypedDecoder           //
+//                        //
+////////////////////////////
+
+type (
+	// TypedDecode parses bytes to a T.
+	TypedDecode[T any] func(data []byte, v *T) error
+
+	// A TypedDecoder reads blocks and decodes them in T values.
+	TypedDecoder[T any] struct {
+		d *Decoder
+	}
+)
+
+// DecodeOf adapts h to a TypedDecode (e.g. DecodeOf[payload](json.Unmarshal)).
+func DecodeOf[T any](h Decode) TypedDecode[T] {
+	return func(data []byte, v *T) error {
+		return h(data, v)
+	}
+}
+
+// NewTypedDecoder decodes values from r using the given h.
+// Provided r must be a block reader (e.g. NewReader16) and buf must be large enough to handle blocks.
+func NewTypedDecoder[T any](r io.Reader, h TypedDecode[T], buf []byte) *TypedDecoder[T] {
+	return &TypedDecoder[T]{
+		d: NewBlockDecoder(r, func(data []byte, v any) error {
+			return h(data, v.(*T))
+		}, buf),
+	}
+}
+
+// NewBlock8TypedDecoder decodes values from r using the given h from Block8.
+func NewBlock8TypedDecoder[T any](r io.Reader, h TypedDecode[T]) *TypedDecoder[T] {
+	return NewTypedDecoder(NewReader8(r), h, make([]byte, MaxBlock8))
+}
+
+// NewBlock16TypedDecoder decodes values from r using the given h from Block16.
+func NewBlock16TypedDecoder[T any](r io.Reader, h TypedDecode[T]) *TypedDecoder[T] {
+	return NewTypedDecoder(NewReader16(r), h, make([]byte, MaxBlock16))
+}
+
+// NewBlock24TypedDecoder decodes values from r using the given h from Block24.
+func NewBlock24TypedDecoder[T any](r io.Reader, h TypedDecode[T]) *TypedDecoder[T] {
+	return NewTypedDecoder(NewReader24(r), h, make([]byte, MaxBlock24))
+}
+
+// NewBlock24CustomTypedDecoder decodes values from r using the given h from Block24.
+func NewBlock24CustomTypedDecoder[T any](r io.Reader, size int, h TypedDecode[T]) (*TypedDecoder[T], error) {
+	br, err := NewReader24Custom(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedDecoder(br, h, make([]byte, size)), nil
+}
+
+// NewBlock32TypedDecoder decodes values from r using the given h from Block32.
+func NewBlock32TypedDecoder[T any](r io.Reader, h TypedDecode[T]) *TypedDecoder[T] {
+	return NewTypedDecoder(NewReader32(r), h, make([]byte, MaxBlock32))
+}
+
+// NewBlock32CustomTypedDecoder decodes values from r using the given h from Block32.
+func NewBlock32CustomTypedDecoder[T any](r io.Reader, size int, h TypedDecode[T]) (*TypedDecoder[T], error) {
+	br, err := NewReader32Custom(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return NewTypedDecoder(br, h, make([]byte, size)), nil
+}
+
+// Read reads from its block reader and returns the deserialized value.
+func (d *TypedDecoder[T]) Read() (T, error) {
+	var v T
+	err := d.d.Read(&v)
+	return v, err
+}
+
+// ReadInto reads from its block reader and deserializes data in v.
+func (d *TypedDecoder[T]) ReadInto(v *T) error {
+	return d.d.Read(v)
+}
+
+////////////////////////////
+//                        //
+// TypedEncoder           //
+//                        //
+////////////////////////////
+
+type (
+	// TypedEncode generates serialized bytes from a T.
+	TypedEncode[T any] func(v T) ([]byte, error)
+
+	// A TypedEncoder encodes T values and writes them as blocks.
+	TypedEncoder[T any] struct {
+		e *Encoder
+	}
+)
+
+// EncodeOf adapts h to a TypedEncode (e.g. EncodeOf[payload](json.Marshal)).
+func EncodeOf[T any](h Encode) TypedEncode[T] {
+	return func(v T) ([]byte, error) {
+		return h(v)
+	}
+}
+
+// NewTypedEncoder encodes values to w using the given h.
+// Provided w must be a block writer (e.g. NewWriter16).
+func NewTypedEncoder[T any](w io.Writer, h TypedEncode[T]) *TypedEncoder[T] {
+	return &TypedEncoder[T]{
+		e: NewBlockEncoder(w, func(v any) ([]byte, error) {
+			return h(v.(T))
+		}),
+	}
+}
+
+// NewBlock8TypedEncoder encodes values to w using the given h in Block8.
+func NewBlock8TypedEncoder[T any](w io.Writer, h TypedEncode[T]) *TypedEncoder[T] {
+	return NewTypedEncoder(NewWriter8(w), h)
+}
+
+// NewBlock16TypedEncoder encodes values to w using the given h in Block16.
+func NewBlock16TypedEncoder[T any](w io.Writer, h TypedEncode[T]) *TypedEncoder[T] {
+	return NewTypedEncoder(NewWriter16(w), h)
+}
+
+// NewBlock24TypedEncoder encodes values to w using the given h in Block24.
+func NewBlock24TypedEncoder[T any](w io.Writer, h TypedEncode[T]) *TypedEncoder[T] {
+	return NewTypedEncoder(NewWriter24(w), h)
+}
+
+// NewBlock32TypedEncoder encodes values to w using the given h in Block32.
+func NewBlock32TypedEncoder[T any](w io.Writer, h TypedEncode[T]) *TypedEncoder[T] {
+	return NewTypedEncoder(NewWriter32(w), h)
+}
+
+// Write writes marshalized bytes to its writer of the given v.
+func (e *TypedEncoder[T]) Write(v T) error {
+	return e.e.Write(v)
+}
@@ -0,0 +1,118 @@
+package blockio_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/mdouchement/blockio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedEncoder_Write(t *testing.T) {
+	type data struct {
+		Field1 string
+		Field2 int
+	}
+
+	var buf bytes.Buffer
+
+	encoder := blockio.NewTypedEncoder(blockio.NewWriter8(&buf), blockio.EncodeOf[data](json.Marshal))
+	err := encoder.Write(data{Field1: "test", Field2: 42})
+	assert.NoError(t, err)
+	assert.Equal(t, "\x1d{\"Field1\":\"test\",\"Field2\":42}", buf.String())
+
+	//
+
+	buf.Reset()
+	iencoder := blockio.NewTypedEncoder(blockio.NewWriter16(&buf), func(v int) ([]byte, error) {
+		return []byte(strconv.Itoa(v)), nil
+	})
+	err = iencoder.Write(42)
+	assert.NoError(t, err)
+	assert.Equal(t, "\x00\x0242", buf.String())
+}
+
+func TestTypedDecoder_Read(t *testing.T) {
+	type data struct {
+		Field1 string
+		Field2 int
+	}
+
+	buf := bytes.NewBufferString("\x1d{\"Field1\":\"test\",\"Field2\":42}\x1d{\"Field1\":\"tset\",\"Field2\":24}")
+	decoder := blockio.NewTypedDecoder(blockio.NewReader8(buf), blockio.DecodeOf[data](json.Unmarshal), make([]byte, blockio.MaxBlock8))
+
+	v, err := decoder.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, data{Field1: "test", Field2: 42}, v)
+
+	err = decoder.ReadInto(&v)
+	assert.NoError(t, err)
+	assert.Equal(t, data{Field1: "tset", Field2: 24}, v)
+
+	_, err = decoder.Read()
+	assert.ErrorIs(t, err, io.EOF)
+
+	//
+
+	buf = bytes.NewBufferString("\x00\x0242")
+	idecoder := blockio.NewTypedDecoder(blockio.NewReader16(buf), func(data []byte, v *int) (err error) {
+		*v, err = strconv.Atoi(string(data))
+		return err
+	}, make([]byte, blockio.MaxBlock16))
+
+	i, err := idecoder.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, i)
+}
+
+func TestTyped_BlockWidths(t *testing.T) {
+	type data struct {
+		Field1 string
+		Field2 int
+	}
+	value := data{Field1: "test", Field2: 42}
+
+	var buf bytes.Buffer
+	assert.NoError(t, blockio.NewBlock8TypedEncoder(&buf, blockio.EncodeOf[data](json.Marshal)).Write(value))
+	v, err := blockio.NewBlock8TypedDecoder(&buf, blockio.DecodeOf[data](json.Unmarshal)).Read()
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+
+	assert.NoError(t, blockio.NewBlock16TypedEncoder(&buf, blockio.EncodeOf[data](json.Marshal)).Write(value))
+	v, err = blockio.NewBlock16TypedDecoder(&buf, blockio.DecodeOf[data](json.Unmarshal)).Read()
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+
+	assert.NoError(t, blockio.NewBlock24TypedEncoder(&buf, blockio.EncodeOf[data](json.Marshal)).Write(value))
+	v, err = blockio.NewBlock24TypedDecoder(&buf, blockio.DecodeOf[data](json.Unmarshal)).Read()
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+
+	//
+	// Custom sizes
+
+	assert.NoError(t, blockio.NewBlock24TypedEncoder(&buf, blockio.EncodeOf[data](json.Marshal)).Write(value))
+	decoder, err := blockio.NewBlock24CustomTypedDecoder(&buf, 64, blockio.DecodeOf[data](json.Unmarshal))
+	assert.NoError(t, err)
+	v, err = decoder.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+
+	// NewBlock32TypedEncoder allocates MaxBlock32 bytes, the custom writer is used instead.
+	w, err := blockio.NewWriter32Custom(&buf, 64)
+	assert.NoError(t, err)
+	assert.NoError(t, blockio.NewTypedEncoder(w, blockio.EncodeOf[data](json.Marshal)).Write(value))
+	decoder, err = blockio.NewBlock32CustomTypedDecoder(&buf, 64, blockio.DecodeOf[data](json.Unmarshal))
+	assert.NoError(t, err)
+	v, err = decoder.Read()
+	assert.NoError(t, err)
+	assert.Equal(t, value, v)
+
+	_, err = blockio.NewBlock24CustomTypedDecoder(&buf, blockio.MaxBlock24+1, blockio.DecodeOf[data](json.Unmarshal))
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+	_, err = blockio.NewBlock32CustomTypedDecoder(&buf, blockio.MaxBlock32+1, blockio.DecodeOf[data](json.Unmarshal))
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+}
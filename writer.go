@@ -13,6 +13,7 @@ type writer struct {
 	dst   io.Writer
 	buf   []byte
 	wsize func(l int) (int, error)
+	wrote int // Bytes written to dst by the last Write.
 }
 
 // NewWriter8 returns a new writer that is able to write blocks of size up to MaxBlock8.
@@ -70,6 +71,29 @@ func NewWriter24(w io.Writer) io.Writer {
 	return w24
 }
 
+// NewWriter24Custom returns a new writer that is able to write blocks of size up to the given size (at most MaxBlock24).
+func NewWriter24Custom(w io.Writer, size int) (io.Writer, error) {
+	if size > MaxBlock24 {
+		return nil, ErrSizeTooLarge
+	}
+
+	w24c := &writer{
+		dst: w,
+		buf: make([]byte, size+4),
+	}
+	w24c.wsize = func(l int) (int, error) {
+		if l > size {
+			return 0, ErrBlockSize
+		}
+
+		binary.BigEndian.PutUint32(w24c.buf[:4], uint32(l))
+		w24c.buf[0], w24c.buf[1], w24c.buf[2] = w24c.buf[1], w24c.buf[2], w24c.buf[3] // Translate over 3 bytes because we work on 24bit.
+		return 3, nil
+	}
+
+	return w24c, nil
+}
+
 // NewWriter32 returns a new writer that is able to write blocks of size up to MaxBlock32.
 func NewWriter32(w io.Writer) io.Writer {
 	w32 := &writer{
@@ -88,12 +112,43 @@ func NewWriter32(w io.Writer) io.Writer {
 	return w32
 }
 
+// NewWriter32Custom returns a new writer that is able to write blocks of size up to the given size (at most MaxBlock32).
+func NewWriter32Custom(w io.Writer, size int) (io.Writer, error) {
+	if size > MaxBlock32 {
+		return nil, ErrSizeTooLarge
+	}
+
+	w32c := &writer{
+		dst: w,
+		buf: make([]byte, size+4),
+	}
+	w32c.wsize = func(l int) (int, error) {
+		if l > size {
+			return 0, ErrBlockSize
+		}
+
+		binary.BigEndian.PutUint32(w32c.buf[:4], uint32(l))
+		return 4, nil
+	}
+
+	return w32c, nil
+}
+
 func (w *writer) Write(block []byte) (n int, err error) {
+	w.wrote = 0
+
 	n, err = w.wsize(len(block))
 	if err != nil {
 		return 0, err
 	}
 
 	n += copy(w.buf[n:], block)
-	return w.dst.Write(w.buf[:n])
+	n, err = w.dst.Write(w.buf[:n])
+	w.wrote = n
+	return n, err
+}
+
+// written returns the number of bytes written to the destination by the last Write.
+func (w *writer) written() int {
+	return w.wrote
 }
@@ -233,3 +233,57 @@ func TestWriter32_Write(t *testing.T) {
 	// assert.ErrorIs(t, err, blockio.ErrBlockSize)
 	// assert.Equal(t, 0, n)
 }
+
+func TestWriter24Custom_Write(t *testing.T) {
+	var buf bytes.Buffer
+
+	//
+	// Size too large
+	_, err := blockio.NewWriter24Custom(&buf, blockio.MaxBlock24+1)
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	w, err := blockio.NewWriter24Custom(&buf, 5)
+	assert.NoError(t, err)
+
+	//
+	// Write `datum`
+
+	n, err := w.Write([]byte("datum"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5+3, n)
+	assert.Equal(t, []byte{0, 0, 5, 'd', 'a', 't', 'u', 'm'}, buf.Bytes())
+
+	//
+	// Block out of limit
+
+	n, err = w.Write([]byte("datums"))
+	assert.ErrorIs(t, err, blockio.ErrBlockSize)
+	assert.Equal(t, 0, n)
+}
+
+func TestWriter32Custom_Write(t *testing.T) {
+	var buf bytes.Buffer
+
+	//
+	// Size too large
+	_, err := blockio.NewWriter32Custom(&buf, blockio.MaxBlock32+1)
+	assert.ErrorIs(t, err, blockio.ErrSizeTooLarge)
+
+	w, err := blockio.NewWriter32Custom(&buf, 5)
+	assert.NoError(t, err)
+
+	//
+	// Write `datum`
+
+	n, err := w.Write([]byte("datum"))
+	assert.NoError(t, err)
+	assert.Equal(t, 5+4, n)
+	assert.Equal(t, []byte{0, 0, 0, 5, 'd', 'a', 't', 'u', 'm'}, buf.Bytes())
+
+	//
+	// Block out of limit
+
+	n, err = w.Write([]byte("datums"))
+	assert.ErrorIs(t, err, blockio.ErrBlockSize)
+	assert.Equal(t, 0, n)
+}